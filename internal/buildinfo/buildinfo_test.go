@@ -0,0 +1,57 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	oldRead, oldVersion, oldCommit, oldBuildTime := readBuildInfo, Version, Commit, BuildTime
+	t.Cleanup(func() {
+		readBuildInfo, Version, Commit, BuildTime = oldRead, oldVersion, oldCommit, oldBuildTime
+	})
+
+	vcs := &debug.BuildInfo{
+		Main: debug.Module{Version: "v1.2.3-0.20260101000000-abcdef123456"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "abcdef1234567890"},
+			{Key: "vcs.time", Value: "2026-01-01T00:00:00Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+	tests := []struct {
+		name                       string
+		ldflags                    [3]string
+		info                       *debug.BuildInfo
+		version, commit, buildTime string
+		modified                   bool
+	}{
+		{
+			"ldflags", [3]string{"v1.0.0", "0123456", "2026-02-02T00:00:00Z"}, vcs,
+			"v1.0.0", "0123456", "2026-02-02T00:00:00Z", true,
+		},
+		{
+			"toolchain", [3]string{}, vcs,
+			"v1.2.3-0.20260101000000-abcdef123456", "abcdef1234567890", "2026-01-01T00:00:00Z", true,
+		},
+		{"no vcs", [3]string{}, &debug.BuildInfo{}, "(devel)", "", "", false},
+		{"no build info", [3]string{}, nil, "(devel)", "", "", false},
+	}
+	for _, tt := range tests {
+		Version, Commit, BuildTime = tt.ldflags[0], tt.ldflags[1], tt.ldflags[2]
+		readBuildInfo = func() (*debug.BuildInfo, bool) { return tt.info, tt.info != nil }
+
+		want := Info{
+			Version:   tt.version,
+			Commit:    tt.commit,
+			BuildTime: tt.buildTime,
+			Modified:  tt.modified,
+			GoVersion: runtime.Version(),
+			Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		}
+		if got := get(); got != want {
+			t.Errorf("%s: get() = %+v, want %+v", tt.name, got, want)
+		}
+	}
+}
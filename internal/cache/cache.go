@@ -3,12 +3,18 @@ package cache
 import (
 	"context"
 	"log/slog"
+	"net"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/redis/rueidis"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 
 	"github.com/tlipoca9/asta/internal/config"
+	"github.com/tlipoca9/asta/pkg/rueidisx"
 )
 
 var (
@@ -23,6 +29,8 @@ type Service interface {
 type service struct {
 	log    *slog.Logger
 	client rueidis.Client
+	// raw is the client without telemetry, for the health checks
+	raw rueidis.Client
 }
 
 type Config struct {
@@ -32,7 +40,7 @@ type Config struct {
 func New() Service {
 	if _s == nil {
 		_init.Do(func() {
-			_s = newService(slog.Default(), Config{
+			_s = newService(config.Logger("cache"), Config{
 				Address: config.C.Cache.Address,
 			})
 		})
@@ -45,21 +53,37 @@ func newService(log *slog.Logger, conf Config) Service {
 	if err != nil {
 		panic(err)
 	}
+	metrics := rueidisx.NewMetrics(config.MetricsNamespace)
+	prometheus.MustRegister(metrics)
 	config.DeferShutdown("cache", cli.Close)
 
 	s := &service{
 		log:    log,
-		client: cli,
+		client: rueidisx.NewClient(cli, rueidisx.WithMetrics(metrics), rueidisx.WithAttributes(serverAttrs(conf.Address)...)),
+		raw:    cli,
 	}
 	return s
 }
 
+func serverAttrs(addr string) []attribute.KeyValue {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return []attribute.KeyValue{semconv.ServerAddress(addr)}
+	}
+	ret := []attribute.KeyValue{semconv.ServerAddress(host)}
+	if n, err := strconv.Atoi(port); err == nil {
+		ret = append(ret, semconv.ServerPort(n))
+	}
+	return ret
+}
+
 func (s *service) Health() bool {
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
 
-	pingCmd := s.client.B().Ping().Build()
-	err := s.client.Do(ctx, pingCmd).Error()
+	// untraced, or each probe would be a root span
+	pingCmd := s.raw.B().Ping().Build()
+	err := s.raw.Do(ctx, pingCmd).Error()
 	if err != nil {
 		s.log.Error("failed to ping cache", "error", err)
 		return false
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+
+	"github.com/tlipoca9/errors"
+	"github.com/urfave/cli/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/tlipoca9/asta/pkg/logx"
+	"github.com/tlipoca9/asta/pkg/rotatex"
+)
+
+// Init loads the config from the files, environment variables and flags of c,
+// then bootstraps the application with it. It is what the serve command runs.
+func Init(c *cli.Context) error {
+	opts := OptionsFromCLI(c)
+	cfg, _, err := Load(opts)
+	if err != nil {
+		return err
+	}
+	return Bootstrap(cfg, opts)
+}
+
+// Bootstrap makes cfg the global config, sets up errors, logger, otel logs, tracer and meter
+// accordingly, then watches the config files of opts for changes. Everything
+// which has to be closed is registered with DeferShutdown.
+func Bootstrap(cfg *Config, opts Options) error {
+	C, loadOpts = *cfg, opts
+	current.Store(cfg)
+
+	initErrors()
+	if err := initLogger(); err != nil {
+		return err
+	}
+	log.Info("config loaded", slog.String("profile", C.Profile), slog.Any("config", C))
+	initResource()
+	if err := initLogs(); err != nil {
+		return err
+	}
+	if err := initTracer(); err != nil {
+		return err
+	}
+	if err := initMeter(); err != nil {
+		return err
+	}
+	return watch()
+}
+
+func initErrors() {
+	errors.C.Style = errors.StyleStack
+	errors.C.StackFramesHandler = errors.JSONStackFramesHandler
+}
+
+func initLogger() error {
+	logLevels.SetDefault(levelOf(&C))
+	logLevels.SetOverrides(overridesOf(&C))
+	// changes made by /debug/loglevel last until the reload of the level they changed
+	Subscribe("logger-level", func(old, cfg *Config) {
+		if old.Service.Debug != cfg.Service.Debug {
+			logLevels.SetDefault(levelOf(cfg))
+		}
+		if !maps.Equal(old.Service.LogLevels, cfg.Service.LogLevels) {
+			logLevels.SetOverrides(overridesOf(cfg))
+		}
+	})
+	watchLogLevelSignal()
+
+	sinks := C.Log.Sinks
+	if len(sinks) == 0 {
+		sinks = []LogSink{{Type: LogSinkStderr}}
+	}
+	hs := make([]slog.Handler, 0, len(sinks))
+	for i, sink := range sinks {
+		h, err := newLogSink(sink)
+		if err != nil {
+			return errors.Wrapf(err, "create log.sinks[%d] failed", i)
+		}
+		hs = append(hs, h)
+	}
+	logHandler = logx.NewFanoutHandler(hs...)
+	setLogger(logHandler)
+	return nil
+}
+
+func replaceAttr(groups []string, a slog.Attr) slog.Attr {
+	a = redactAttr(groups, a)
+
+	if a.Key == "error" || a.Key == "err" {
+		return logx.JSON(a.Key, a.Value.Any())
+	}
+
+	if v, ok := a.Value.Any().(map[string]any); ok {
+		return logx.JSON(a.Key, v)
+	}
+
+	if v, ok := a.Value.Any().([]map[string]any); ok {
+		return logx.JSON(a.Key, v)
+	}
+
+	if v, ok := a.Value.Any().([]any); ok {
+		return logx.JSON(a.Key, v)
+	}
+
+	return a
+}
+
+// setLogger makes h the handler of the config and default loggers, behind
+// their level and the attributes of the context. The context may enable debug
+// records regardless of the level, see logx.WithDebug.
+func setLogger(h slog.Handler) {
+	rootHandler = h
+	log = slog.New(logx.NewContextHandler(logx.NewLevelHandler(logLevels.Leveler(""), h)))
+	slog.SetDefault(log)
+}
+
+func levelOf(cfg *Config) slog.Level {
+	if cfg.Service.Debug {
+		return slog.LevelDebug
+	}
+	return slog.LevelInfo
+}
+
+// initResource detects the otel resource, a partially detected resource is
+// still used as failing detectors only miss their own attributes.
+func initResource() {
+	res, err := newResource(context.Background())
+	if err != nil {
+		log.Warn("otel resource is incomplete", "error", err)
+	}
+	if res != nil {
+		otelResource = res
+	}
+}
+
+func initTracer() error {
+	var exporter trace.SpanExporter
+	switch {
+	case C.Otel.CollectorEndpoint != "":
+		var err error
+		exporter, err = newOTLPTraceExporter(context.Background())
+		if err != nil {
+			return err
+		}
+	default:
+		f := C.Otel.File
+		out, err := rotatex.New(f.Path, rotatex.Options{
+			MaxSize:  int64(f.MaxSizeMB) << 20,
+			Interval: f.RotateInterval,
+			MaxFiles: f.MaxFiles,
+			Compress: f.Compress,
+		})
+		if err != nil {
+			return errors.Wrap(err, "open trace file failed")
+		}
+		// registered before the tracer provider, so that it is closed after the last flush
+		DeferShutdown("trace-file", out.Close)
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(out))
+		if err != nil {
+			return errors.Wrap(err, "create trace file exporter failed")
+		}
+	}
+
+	tp := trace.NewTracerProvider(
+		trace.WithSampler(newSampler()),
+		trace.WithSpanProcessor(newSpanProcessor(exporter)),
+		trace.WithResource(Resource()),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(newPropagator())
+
+	DeferShutdown("tracer-provider", tp.Shutdown)
+	return nil
+}
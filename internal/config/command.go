@@ -0,0 +1,328 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/tlipoca9/errors"
+	"github.com/urfave/cli/v2"
+
+	"github.com/tlipoca9/asta/pkg/logx"
+)
+
+// Command returns the `config` command, which inspects the configuration
+// without starting anything.
+func Command() *cli.Command {
+	return &cli.Command{
+		Name:  "config",
+		Usage: "inspect the configuration",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "validate",
+				Usage:  "validate the effective configuration",
+				Action: validateAction,
+			},
+			{
+				Name:  "print",
+				Usage: "print the effective configuration with the source of each value",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "redacted", Value: true, Usage: "mask secret values"},
+				},
+				Action: printAction,
+			},
+			{
+				Name:   "sample",
+				Usage:  "print a commented sample configuration in toml",
+				Action: sampleAction,
+			},
+			{
+				Name:  "sign-debug-log",
+				Usage: "print a value of the debug log header signed with service.debug_log.key",
+				Flags: []cli.Flag{
+					&cli.DurationFlag{
+						Name:  "ttl",
+						Value: 15 * time.Minute,
+						Usage: "validity of the value, at most service.debug_log.max_ttl",
+					},
+				},
+				Action: signDebugLogAction,
+			},
+			{
+				Name:  "secrets",
+				Usage: "manage the encrypted secrets file",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "keygen",
+						Usage:  "print a new secrets key",
+						Action: secretsKeygenAction,
+					},
+					{
+						Name:  "encrypt",
+						Usage: "encrypt a json object of secrets into a secrets file",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "in", Usage: "json file to encrypt, stdin if empty"},
+							&cli.StringFlag{Name: "out", Usage: "secrets file to write", Required: true},
+							&cli.StringFlag{Name: "key-env", Value: "ASTA_SECRETS_KEY", Usage: "environment variable holding the key"},
+						},
+						Action: secretsEncryptAction,
+					},
+				},
+			},
+		},
+	}
+}
+
+func validateAction(c *cli.Context) error {
+	if _, _, err := Load(OptionsFromCLI(c)); err != nil {
+		return cli.Exit(err, 1)
+	}
+	_, err := fmt.Fprintln(c.App.Writer, "config is valid")
+	return err
+}
+
+func printAction(c *cli.Context) error {
+	cfg, sources, err := Load(OptionsFromCLI(c))
+	if cfg == nil {
+		return cli.Exit(err, 1)
+	}
+
+	w := tabwriter.NewWriter(c.App.Writer, 0, 0, 1, ' ', 0)
+	if cfg.Profile != "" {
+		_, _ = fmt.Fprintf(w, "# profile: %s\n", cfg.Profile)
+	}
+	v := reflect.ValueOf(cfg).Elem()
+	for _, f := range configFields() {
+		source, ok := sources[f.Key]
+		if !ok {
+			source = "unset"
+		}
+		mask := c.Bool("redacted") && f.Tag.Get("secret") == "true"
+		_, _ = fmt.Fprintf(w, "%s\t= %s\t# %s\n", f.Key, tomlValue(v.FieldByIndex(f.Index), mask), source)
+	}
+	if flushErr := w.Flush(); flushErr != nil {
+		return flushErr
+	}
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+	return nil
+}
+
+func sampleAction(c *cli.Context) error {
+	_, err := io.WriteString(c.App.Writer, Sample())
+	return err
+}
+
+func signDebugLogAction(c *cli.Context) error {
+	cfg, _, err := Load(OptionsFromCLI(c))
+	if err != nil {
+		return cli.Exit(err, 1)
+	}
+	dl := cfg.Service.DebugLog
+	if dl.Key == "" {
+		return cli.Exit("service.debug_log.key is not set", 1)
+	}
+	if c.Duration("ttl") > dl.MaxTTL {
+		return cli.Exit(fmt.Sprintf("ttl must be at most service.debug_log.max_ttl (%s)", dl.MaxTTL), 1)
+	}
+	value := logx.SignDebugLog([]byte(dl.Key), time.Now().Add(c.Duration("ttl")))
+	_, err = fmt.Fprintf(c.App.Writer, "%s: %s\n", dl.Header, value)
+	return err
+}
+
+func secretsKeygenAction(c *cli.Context) error {
+	key, err := NewSecretsKey()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(c.App.Writer, key)
+	return err
+}
+
+func secretsEncryptAction(c *cli.Context) error {
+	var (
+		data []byte
+		err  error
+	)
+	if in := c.String("in"); in != "" {
+		data, err = os.ReadFile(in)
+	} else {
+		data, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return errors.Wrap(err, "read secrets failed")
+	}
+
+	var secrets map[string]string
+	if err := json.Unmarshal(data, &secrets); err != nil {
+		return errors.Wrap(err, "secrets must be a json object of strings")
+	}
+	sealed, err := EncryptSecrets(os.Getenv(c.String("key-env")), secrets)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(os.WriteFile(c.String("out"), sealed, 0o600), "write secrets file failed")
+}
+
+// Sample returns a toml config with every field set to its default value and
+// commented with its usage, rules and environment variable.
+func Sample() string {
+	var b strings.Builder
+	writeSample(&b, reflect.TypeOf(Config{}), "")
+	return strings.TrimLeft(b.String(), "\n")
+}
+
+func writeSample(b *strings.Builder, t reflect.Type, prefix string) {
+	var tables []reflect.StructField
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		key := sampleKey(prefix, sf)
+		if key == "" {
+			continue
+		}
+		if sf.Type.Kind() == reflect.Struct || sf.Type.Kind() == reflect.Map {
+			tables = append(tables, sf)
+			continue
+		}
+
+		writeSampleComment(b, key, sf.Tag)
+		v := reflect.New(sf.Type).Elem()
+		if def, ok := sf.Tag.Lookup("default"); ok {
+			v = parseDefault(sf.Type, def)
+		}
+		fmt.Fprintf(b, "%s = %s\n", key[strings.LastIndex(key, ".")+1:], tomlValue(v, false))
+	}
+
+	for _, sf := range tables {
+		key := sampleKey(prefix, sf)
+		b.WriteString("\n")
+		if sf.Type.Kind() == reflect.Map {
+			writeSampleComment(b, key, sf.Tag)
+		}
+		fmt.Fprintf(b, "[%s]\n", key)
+		if sf.Type.Kind() == reflect.Struct {
+			writeSample(b, sf.Type, key)
+		}
+	}
+}
+
+func writeSampleComment(b *strings.Builder, key string, tag reflect.StructTag) {
+	if usage := usages[key]; usage != "" {
+		fmt.Fprintf(b, "# %s\n", usage)
+	}
+	var notes []string
+	if rules := tag.Get("validate"); rules != "" {
+		notes = append(notes, "rules: "+rules)
+	}
+	if tag.Get("live") == "true" {
+		notes = append(notes, "reloaded live")
+	}
+	if tag.Get("secret") == "true" {
+		notes = append(notes, "secret")
+	}
+	notes = append(notes, "env: "+EnvName(key))
+	fmt.Fprintf(b, "# %s\n", strings.Join(notes, ", "))
+}
+
+func sampleKey(prefix string, sf reflect.StructField) string {
+	if !sf.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		name = strings.ToLower(sf.Name)
+	}
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func parseDefault(t reflect.Type, def string) reflect.Value {
+	v := reflect.New(t).Elem()
+	switch {
+	case t == durationType:
+		d, _ := time.ParseDuration(def)
+		v.SetInt(int64(d))
+	case t.Kind() == reflect.String:
+		v.SetString(def)
+	case t.Kind() == reflect.Bool:
+		b, _ := strconv.ParseBool(def)
+		v.SetBool(b)
+	case t.Kind() == reflect.Int:
+		n, _ := strconv.ParseInt(def, 10, 64)
+		v.SetInt(n)
+	case t.Kind() == reflect.Float64:
+		n, _ := strconv.ParseFloat(def, 64)
+		v.SetFloat(n)
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String:
+		v = reflect.ValueOf(strings.Split(def, ","))
+	}
+	return v
+}
+
+var bareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// tomlValue formats v as a toml value, masking non-empty values if mask is set.
+func tomlValue(v reflect.Value, mask bool) string {
+	switch {
+	case v.Type() == durationType:
+		return strconv.Quote(time.Duration(v.Int()).String())
+	case v.Kind() == reflect.String:
+		if mask && v.String() != "" {
+			return strconv.Quote(Redacted)
+		}
+		return strconv.Quote(v.String())
+	case v.Kind() == reflect.Map:
+		keys := make([]string, 0, v.Len())
+		for _, k := range v.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		slices.Sort(keys)
+		items := make([]string, 0, len(keys))
+		for _, k := range keys {
+			name := k
+			if !bareKey.MatchString(k) {
+				name = strconv.Quote(k)
+			}
+			items = append(items, name+" = "+tomlValue(v.MapIndex(reflect.ValueOf(k)), mask))
+		}
+		if len(items) == 0 {
+			return "{}"
+		}
+		return "{ " + strings.Join(items, ", ") + " }"
+	case v.Kind() == reflect.Struct:
+		items := make([]string, 0, v.NumField())
+		for _, f := range fields(v.Type()) {
+			fv := v.FieldByIndex(f.Index)
+			if fv.IsZero() {
+				continue
+			}
+			items = append(items, f.Key+" = "+tomlValue(fv, mask && f.Tag.Get("secret") == "true"))
+		}
+		return "{ " + strings.Join(items, ", ") + " }"
+	case v.Kind() == reflect.Slice:
+		items := make([]string, 0, v.Len())
+		for i := range v.Len() {
+			items = append(items, tomlValue(v.Index(i), mask))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case v.Kind() == reflect.Bool, v.Kind() == reflect.Int, v.Kind() == reflect.Float64:
+		return fmt.Sprint(v.Interface())
+	default:
+		b, _ := json.Marshal(Redact(v.Interface()))
+		return string(b)
+	}
+}
@@ -1,177 +1,275 @@
 package config
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
 	"log/slog"
-	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-viper/mapstructure/v2"
-	"github.com/knadh/koanf/parsers/toml"
-	"github.com/knadh/koanf/providers/file"
 	"github.com/knadh/koanf/v2"
-	"github.com/lmittmann/tint"
-	"github.com/mattn/go-colorable"
 	"github.com/tlipoca9/errors"
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
-	"go.opentelemetry.io/otel/propagation"
-	"go.opentelemetry.io/otel/sdk/resource"
-	"go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
 
 	"github.com/tlipoca9/asta/pkg/logx"
 )
 
 var (
-	C   Config
-	log *slog.Logger
+	// C is the config loaded at startup, it never changes afterwards.
+	// Use Current for the live config.
+	C Config
+
+	current  atomic.Pointer[Config]
+	loadOpts Options
+	log      = slog.Default()
+	// logLevels gate the default logger and those of Logger.
+	logLevels logx.Levels
+	// logHandler is the handler of initLogger, without the attributes of the context.
+	logHandler slog.Handler
+	// rootHandler is the handler shared by the loggers, see setLogger.
+	rootHandler slog.Handler
 )
 
 type Config struct {
+	// Profile is the active profile, see Options.Profile.
+	Profile string `json:"-"`
+
 	Service struct {
-		Name            string        `json:"name"`
-		Addr            string        `json:"addr"`
-		ShutdownTimeout time.Duration `json:"shutdown_timeout"`
+		Name            string        `json:"name"             default:"asta"  validate:"required"`
+		Addr            string        `json:"addr"             default:":8080" validate:"required,hostport"`
+		ShutdownTimeout time.Duration `json:"shutdown_timeout" default:"5s"    validate:"gt=0"              live:"true"`
 		Console         bool          `json:"console"`
-		Debug           bool          `json:"debug"`
+		Debug           bool          `json:"debug"                                                         live:"true"`
+
+		LogLevels  map[string]string `json:"log_levels"  validate:"loglevel"               live:"true"`
+		AdminToken string            `json:"admin_token"                     secret:"true" live:"true"`
+
+		DebugLog struct {
+			Header       string        `json:"header"        default:"X-Debug-Log" validate:"required"               live:"true"`
+			AllowedCIDRs []string      `json:"allowed_cidrs"                       validate:"cidr"                   live:"true"`
+			Key          string        `json:"key"                                                     secret:"true" live:"true"`
+			MaxTTL       time.Duration `json:"max_ttl"       default:"1h"          validate:"gt=0"                   live:"true"`
+		} `json:"debug_log"`
 	} `json:"service"`
 
+	Log struct {
+		Sinks []LogSink `json:"sinks"`
+	} `json:"log"`
+
 	Otel struct {
 		CollectorEndpoint string `json:"collector_endpoint"`
+		Protocol          string `json:"protocol"           default:"grpc" validate:"oneof=grpc http/protobuf"`
+		Insecure          bool   `json:"insecure"`
+
+		Headers map[string]string `json:"headers" secret:"true"`
+
+		Compression string        `json:"compression" default:"none" validate:"oneof=gzip none"`
+		Timeout     time.Duration `json:"timeout"     default:"10s"  validate:"min=0"`
+
+		Propagators []string `json:"propagators" default:"tracecontext,baggage" validate:"oneof=tracecontext baggage b3 b3multi jaeger xray ot"` //nolint:lll // lists every propagator
+
+		TLS struct {
+			CAFile             string `json:"ca_file"              validate:"file"`
+			CertFile           string `json:"cert_file"            validate:"file"`
+			KeyFile            string `json:"key_file"             validate:"file"`
+			ServerName         string `json:"server_name"`
+			InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+		} `json:"tls"`
+
+		Batch struct {
+			MaxQueueSize       int           `json:"max_queue_size"        default:"2048" validate:"min=1"`
+			MaxExportBatchSize int           `json:"max_export_batch_size" default:"512"  validate:"min=1"`
+			BatchTimeout       time.Duration `json:"batch_timeout"         default:"5s"   validate:"gt=0"`
+			ExportTimeout      time.Duration `json:"export_timeout"        default:"30s"  validate:"gt=0"`
+		} `json:"batch"`
+
+		File struct {
+			Path           string        `json:"path"            default:"run/trace.log" validate:"required"`
+			MaxSizeMB      int           `json:"max_size_mb"     default:"100"           validate:"min=0"`
+			RotateInterval time.Duration `json:"rotate_interval" default:"24h"           validate:"min=0"`
+			MaxFiles       int           `json:"max_files"       default:"7"             validate:"min=0"`
+			Compress       bool          `json:"compress"`
+		} `json:"file"`
+
+		Metrics struct {
+			OTLP     bool          `json:"otlp"`
+			Interval time.Duration `json:"interval" default:"60s"  validate:"gt=0"`
+			Runtime  bool          `json:"runtime"  default:"true"`
+		} `json:"metrics"`
+
+		Logs struct {
+			Enabled bool   `json:"enabled"`
+			Path    string `json:"path"    default:"run/otel-log.log" validate:"required"`
+		} `json:"logs"`
+
+		Sampler struct {
+			Type string `json:"type" default:"parent_ratio" validate:"oneof=always never ratio parent_ratio rules rate_limited"` //nolint:lll // lists every sampler
+
+			Ratio              float64       `json:"ratio"                default:"1"   validate:"min=0,max=1"`
+			RateLimit          float64       `json:"rate_limit"           default:"100" validate:"gt=0"`
+			AlwaysSampleErrors bool          `json:"always_sample_errors"`
+			Rules              []SamplerRule `json:"rules"`
+		} `json:"sampler"`
+
+		Resource struct {
+			Environment string `json:"environment"`
+
+			Detectors []string `json:"detectors" default:"env,host,os,process,container" validate:"oneof=env host os process container"` //nolint:lll // lists every detector
+
+			Attributes map[string]string `json:"attributes"`
+		} `json:"resource"`
 	} `json:"otel"`
 
 	Database struct {
-		DBName   string `json:"db_name"`
-		Username string `json:"username"`
-		Password string `json:"password"`
-		Host     string `json:"host"`
-		Port     int    `json:"port"`
+		DBName   string `json:"db_name"                      validate:"required"`
+		Username string `json:"username"                     validate:"required"`
+		Password string `json:"password"                                                secret:"true"`
+		Host     string `json:"host"     default:"localhost" validate:"required"`
+		Port     int    `json:"port"     default:"3306"      validate:"min=1,max=65535"`
+
+		MaxOpenConns    int           `json:"max_open_conns"     default:"20"  validate:"min=0"`
+		MaxIdleConns    int           `json:"max_idle_conns"     default:"10"  validate:"min=0"`
+		ConnMaxLifetime time.Duration `json:"conn_max_lifetime"  default:"30m" validate:"min=0"`
+		ConnMaxIdleTime time.Duration `json:"conn_max_idle_time" default:"5m"  validate:"min=0"`
 	} `json:"database"`
 
 	Cache struct {
-		Address string `json:"address"`
-	}
+		Address string `json:"address" default:"localhost:6379" validate:"required,hostport"`
+	} `json:"cache"`
+
+	Secrets struct {
+		File   string `json:"file"                               validate:"file"`
+		KeyEnv string `json:"key_env" default:"ASTA_SECRETS_KEY"`
+	} `json:"secrets"`
 }
 
-func initConfig() {
-	k := koanf.New(".")
-	if err := k.Load(file.Provider("etc/config.toml"), toml.Parser()); err != nil {
-		panic(err)
-	}
+// SamplerRule decides the sampling of the spans whose name matches Route, which
+// may end with "*" to match a prefix. The name of a server span is the raw
+// request path, e.g. /users/42 rather than /users/:id. Sample is always, never
+// or ratio, which samples Ratio of the matching traces.
+type SamplerRule struct {
+	Route  string  `json:"route"  validate:"required"`
+	Sample string  `json:"sample" validate:"required,oneof=always never ratio"`
+	Ratio  float64 `json:"ratio"  validate:"min=0,max=1"`
+}
 
-	err := k.UnmarshalWithConf("", nil, koanf.UnmarshalConf{
-		Tag: "json",
-		DecoderConfig: &mapstructure.DecoderConfig{
-			Result:  &C,
-			TagName: "json",
-			DecodeHook: mapstructure.ComposeDecodeHookFunc(
-				mapstructure.StringToTimeDurationHookFunc(),
-				mapstructure.TextUnmarshallerHookFunc(),
-			),
-		},
-	})
-	if err != nil {
-		panic(err)
-	}
+// LogSink is an output of the logs. Format is console or json, service.console
+// decides if empty. Level is the min level written to the sink, the levels of
+// the loggers apply anyway.
+//
+// The file sink writes to Path and rotates it like otel.file. The syslog sink
+// sends to Address over Network, e.g. localhost:514 over udp or /dev/log over
+// unixgram, with Facility (user if empty) and Tag (service.name if empty).
+type LogSink struct {
+	Type   string `json:"type"   validate:"required,oneof=stderr file syslog"`
+	Format string `json:"format" validate:"oneof=console json"`
+	Level  string `json:"level"  validate:"loglevel"`
 
-	b, _ := json.Marshal(C)
-	fmt.Printf("config: %s\n", string(b))
-}
+	Path           string        `json:"path"`
+	MaxSizeMB      int           `json:"max_size_mb"     validate:"min=0"`
+	RotateInterval time.Duration `json:"rotate_interval" validate:"min=0"`
+	MaxFiles       int           `json:"max_files"       validate:"min=0"`
+	Compress       bool          `json:"compress"`
 
-func initErrors() {
-	errors.C.Style = errors.StyleStack
-	errors.C.StackFramesHandler = errors.JSONStackFramesHandler
+	Network  string `json:"network"  validate:"oneof=udp unix unixgram"`
+	Address  string `json:"address"`
+	Facility string `json:"facility" validate:"oneof=user daemon local0 local1 local2 local3 local4 local5 local6 local7"` //nolint:lll // lists every facility
+	Tag      string `json:"tag"`
 }
 
-func initLogger() {
-	var (
-		h        slog.Handler
-		lvl      slog.Level
-		replacer = func(_ []string, a slog.Attr) slog.Attr {
-			if a.Key == "error" || a.Key == "err" {
-				return logx.JSON(a.Key, a.Value.Any())
-			}
-
-			if v, ok := a.Value.Any().(map[string]any); ok {
-				return logx.JSON(a.Key, v)
-			}
+// Current returns the live config, which is replaced as a whole on every
+// successful reload.
+func Current() *Config {
+	if cfg := current.Load(); cfg != nil {
+		return cfg
+	}
+	return &C
+}
 
-			if v, ok := a.Value.Any().([]map[string]any); ok {
-				return logx.JSON(a.Key, v)
-			}
+// Sources maps config keys to the layer their values come from: "default",
+// a config file path, "env" or "flag".
+type Sources map[string]string
 
-			if v, ok := a.Value.Any().([]any); ok {
-				return logx.JSON(a.Key, v)
-			}
+// Options controls where Load reads the config from.
+type Options struct {
+	// Files are loaded in order, later files override earlier ones.
+	// DefaultConfigFile is used if Files is empty and the file exists.
+	Files []string
+	// Overrides are keyed by config keys, e.g. "service.addr", and take
+	// precedence over everything else.
+	Overrides map[string]any
+	// SkipEnv ignores the ASTA_* environment variables.
+	SkipEnv bool
+	// Profile selects the [profiles.<name>] sections which overlay the rest
+	// of their config file.
+	Profile string
+}
 
-			return a
-		}
-	)
-	if C.Service.Debug {
-		lvl = slog.LevelDebug
-	} else {
-		lvl = slog.LevelInfo
-	}
-	if C.Service.Console {
-		h = tint.NewHandler(colorable.NewColorableStderr(), &tint.Options{
-			Level:       lvl,
-			TimeFormat:  time.TimeOnly,
-			ReplaceAttr: replacer,
-		})
-	} else {
-		h = slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
-			Level:       lvl,
-			ReplaceAttr: replacer,
-		})
+// Load reads the config with the precedence defaults < files < env < overrides.
+// It has no side effects, see Bootstrap for applying the config.
+// If the config is invalid, it is returned along with a ValidationError.
+func Load(opts Options) (*Config, Sources, error) {
+	layers, err := configLayers(opts)
+	if err != nil {
+		return nil, nil, err
 	}
-	log = slog.New(logx.NewContextHandler(h))
-	slog.SetDefault(log)
-}
 
-func initTracer() {
-	var tp *trace.TracerProvider
-	switch {
-	case C.Otel.CollectorEndpoint != "":
-		// TODO: implement collector endpoint
-		panic("not implemented")
-	default:
-		err := os.MkdirAll("run", 0o750)
-		if err != nil {
-			panic(err)
+	var (
+		k, sources   = koanf.New("."), make(Sources)
+		profileFound bool
+	)
+	for _, l := range layers {
+		lk := koanf.New(".")
+		if err := lk.Load(l.provider, l.parser); err != nil {
+			return nil, nil, errors.Wrapf(err, "load config from %s failed", l.name)
 		}
-		out, err := os.OpenFile("run/trace.log", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
-		if err != nil {
-			panic(err)
+		// the section of the active profile overlays the rest of its layer
+		profiles := lk.Cut(profilesKey)
+		lk.Delete(profilesKey)
+		for _, key := range lk.Keys() {
+			sources[fieldKey(key)] = l.name
 		}
-		exporter, err := stdouttrace.New(stdouttrace.WithWriter(out))
-		if err != nil {
-			panic(err)
+		if opts.Profile != "" && profiles.Exists(opts.Profile) {
+			profileFound = true
+			overlay := profiles.Cut(opts.Profile)
+			for _, key := range overlay.Keys() {
+				sources[fieldKey(key)] = l.name + " [" + profilesKey + "." + opts.Profile + "]"
+			}
+			if err := lk.Merge(overlay); err != nil {
+				return nil, nil, errors.Wrapf(err, "merge profile %s of %s failed", opts.Profile, l.name)
+			}
 		}
-		tp = trace.NewTracerProvider(
-			trace.WithSampler(trace.AlwaysSample()),
-			trace.WithBatcher(exporter),
-			trace.WithResource(
-				resource.NewWithAttributes(
-					semconv.SchemaURL,
-					semconv.ServiceNameKey.String(C.Service.Name),
-				),
+		if err := k.Merge(lk); err != nil {
+			return nil, nil, errors.Wrapf(err, "merge config from %s failed", l.name)
+		}
+	}
+
+	var cfg Config
+	err = k.UnmarshalWithConf("", nil, koanf.UnmarshalConf{
+		Tag: "json",
+		DecoderConfig: &mapstructure.DecoderConfig{
+			Result:           &cfg,
+			TagName:          "json",
+			WeaklyTypedInput: true,
+			DecodeHook: mapstructure.ComposeDecodeHookFunc(
+				mapstructure.StringToTimeDurationHookFunc(),
+				mapstructure.TextUnmarshallerHookFunc(),
+				jsonToStructSliceHookFunc(),
 			),
-		)
-		otel.SetTracerProvider(tp)
-		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
-			propagation.TraceContext{},
-			propagation.Baggage{},
-		))
+		},
+	})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unmarshal config failed")
 	}
 
-	DeferShutdown("tracer-provider", tp.Shutdown)
-}
+	cfg.Profile = opts.Profile
 
-func init() {
-	initConfig()
-	initErrors()
-	initLogger()
-	initTracer()
+	errs := resolveSecrets(context.Background(), &cfg)
+	if opts.Profile != "" && !profileFound {
+		errs = append(errs, FieldError{Key: "profile", Value: opts.Profile, Message: "no such section in the config files"})
+	}
+	errs = append(errs, cfg.validate()...)
+	if len(errs) > 0 {
+		return &cfg, sources, errs
+	}
+	return &cfg, sources, nil
 }
@@ -0,0 +1,247 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// required are the values without default that Load needs to succeed.
+const required = `
+[database]
+db_name = "asta"
+username = "asta"
+`
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, "config.toml", required+`
+[service]
+addr = ":9000"
+shutdown_timeout = "10s"
+`)
+
+	tests := []struct {
+		name      string
+		files     []string
+		env       map[string]string
+		overrides map[string]any
+		skipEnv   bool
+		// want is the value of service.addr and its source
+		want, source string
+	}{
+		{"defaults", nil, nil, nil, false, ":8080", "default"},
+		{"file", []string{file}, nil, nil, false, ":9000", file},
+		{"env", []string{file}, map[string]string{"ASTA_SERVICE_ADDR": ":9100"}, nil, false, ":9100", "env"},
+		{"skip env", []string{file}, map[string]string{"ASTA_SERVICE_ADDR": ":9100"}, nil, true, ":9000", file},
+		{
+			"override", []string{file}, map[string]string{"ASTA_SERVICE_ADDR": ":9100"},
+			map[string]any{"service.addr": ":9200"}, false, ":9200", "flag",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ASTA_DATABASE_DB_NAME", "asta")
+			t.Setenv("ASTA_DATABASE_USERNAME", "asta")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg, sources, err := Load(Options{Files: tt.files, Overrides: tt.overrides, SkipEnv: tt.skipEnv})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Service.Addr != tt.want {
+				t.Errorf("service.addr = %q, want %q", cfg.Service.Addr, tt.want)
+			}
+			if sources["service.addr"] != tt.source {
+				t.Errorf("source of service.addr = %q, want %q", sources["service.addr"], tt.source)
+			}
+		})
+	}
+}
+
+func TestLoadTypes(t *testing.T) {
+	t.Setenv("ASTA_DATABASE_DB_NAME", "asta")
+	t.Setenv("ASTA_DATABASE_USERNAME", "asta")
+	t.Setenv("ASTA_OTEL_PROPAGATORS", "b3,jaeger")
+	t.Setenv("ASTA_SERVICE_LOG_LEVELS", "db=debug,cache=warn")
+	t.Setenv("ASTA_OTEL_SAMPLER_RULES", `[{"route":"/metrics","sample":"never"}]`)
+	// unknown variables are ignored
+	t.Setenv("ASTA_SERVICE_UNKNOWN", "x")
+
+	cfg, sources, err := Load(Options{Overrides: map[string]any{
+		"service.shutdown_timeout": 3 * time.Second,
+		"otel.sampler.ratio":       0.5,
+		"database.port":            3307,
+		"service.debug":            true,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"b3", "jaeger"}; !reflect.DeepEqual(cfg.Otel.Propagators, want) {
+		t.Errorf("otel.propagators = %v, want %v", cfg.Otel.Propagators, want)
+	}
+	if want := map[string]string{"db": "debug", "cache": "warn"}; !reflect.DeepEqual(cfg.Service.LogLevels, want) {
+		t.Errorf("service.log_levels = %v, want %v", cfg.Service.LogLevels, want)
+	}
+	if want := []SamplerRule{{Route: "/metrics", Sample: "never"}}; !reflect.DeepEqual(cfg.Otel.Sampler.Rules, want) {
+		t.Errorf("otel.sampler.rules = %v, want %v", cfg.Otel.Sampler.Rules, want)
+	}
+	if cfg.Service.ShutdownTimeout != 3*time.Second || cfg.Otel.Sampler.Ratio != 0.5 ||
+		cfg.Database.Port != 3307 || !cfg.Service.Debug {
+		t.Errorf("overrides not applied: %+v", cfg.Service)
+	}
+	for key, want := range map[string]string{
+		"otel.propagators":          "env",
+		"service.log_levels":        "env",
+		"otel.sampler.rules":        "env",
+		"otel.sampler.ratio":        "flag",
+		"otel.sampler.type":         "default",
+		"service.debug_log.max_ttl": "default",
+	} {
+		if sources[key] != want {
+			t.Errorf("source of %s = %q, want %q", key, sources[key], want)
+		}
+	}
+	if _, ok := sources["service.unknown"]; ok {
+		t.Error("unknown environment variable loaded")
+	}
+}
+
+func TestLoadFiles(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "base.toml", required+`
+[service]
+name = "base"
+addr = ":9000"
+
+[otel.headers]
+a = "1"
+`)
+	override := writeFile(t, dir, "override.yaml", `
+service:
+  name: yaml
+otel:
+  headers:
+    b: "2"
+`)
+	last := writeFile(t, dir, "last.json", `{"service": {"name": "json"}, "database": {"port": 3307}}`)
+
+	cfg, sources, err := Load(Options{Files: []string{base, override, last}, SkipEnv: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		key    string
+		got    any
+		want   any
+		source string
+	}{
+		{"service.name", cfg.Service.Name, "json", last},
+		{"service.addr", cfg.Service.Addr, ":9000", base},
+		{"database.port", cfg.Database.Port, 3307, last},
+		{"database.db_name", cfg.Database.DBName, "asta", base},
+		// maps are merged key by key, the source is the last file setting one
+		{"otel.headers", cfg.Otel.Headers, map[string]string{"a": "1", "b": "2"}, override},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.key, tt.got, tt.want)
+		}
+		if sources[tt.key] != tt.source {
+			t.Errorf("source of %s = %q, want %q", tt.key, sources[tt.key], tt.source)
+		}
+	}
+
+	for _, files := range [][]string{
+		{writeFile(t, dir, "config.ini", "")},
+		{base, filepath.Join(dir, "missing.toml")},
+		{writeFile(t, dir, "invalid.toml", "[service")},
+	} {
+		if _, _, err := Load(Options{Files: files, SkipEnv: true}); err == nil {
+			t.Errorf("Load(%v) succeeded", files)
+		}
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "base.toml", required+`
+[service]
+name = "base"
+addr = ":9000"
+
+[profiles.dev.service]
+debug = true
+
+[profiles.prod.service]
+addr = ":80"
+`)
+	prod := writeFile(t, dir, "prod.toml", `
+[profiles.prod.database]
+host = "db.prod"
+`)
+
+	tests := []struct {
+		profile string
+		files   []string
+		want    map[string]string
+		sources map[string]string
+	}{
+		{"", []string{base, prod}, map[string]string{"addr": ":9000", "host": "localhost"}, map[string]string{
+			"service.addr":  base,
+			"database.host": "default",
+		}},
+		{"prod", []string{base, prod}, map[string]string{"addr": ":80", "host": "db.prod"}, map[string]string{
+			"service.addr":  base + " [profiles.prod]",
+			"service.name":  base,
+			"database.host": prod + " [profiles.prod]",
+		}},
+		{"dev", []string{base, prod}, map[string]string{"addr": ":9000", "host": "localhost"}, map[string]string{
+			"service.debug": base + " [profiles.dev]",
+		}},
+	}
+	for _, tt := range tests {
+		cfg, sources, err := Load(Options{Files: tt.files, SkipEnv: true, Profile: tt.profile})
+		if err != nil {
+			t.Fatalf("profile %q: %v", tt.profile, err)
+		}
+		if cfg.Profile != tt.profile {
+			t.Errorf("profile %q: Profile = %q", tt.profile, cfg.Profile)
+		}
+		got := map[string]string{"addr": cfg.Service.Addr, "host": cfg.Database.Host}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("profile %q: values = %v, want %v", tt.profile, got, tt.want)
+		}
+		if cfg.Service.Debug != (tt.profile == "dev") {
+			t.Errorf("profile %q: service.debug = %v", tt.profile, cfg.Service.Debug)
+		}
+		for key, want := range tt.sources {
+			if sources[key] != want {
+				t.Errorf("profile %q: source of %s = %q, want %q", tt.profile, key, sources[key], want)
+			}
+		}
+		if _, ok := sources["profiles"]; ok {
+			t.Errorf("profile %q: the profiles are loaded as a config key", tt.profile)
+		}
+	}
+
+	_, _, err := Load(Options{Files: []string{base, prod}, SkipEnv: true, Profile: "staging"})
+	errs, ok := err.(ValidationError)
+	want := FieldError{Key: "profile", Value: "staging", Message: "no such section in the config files"}
+	if !ok || len(errs) != 1 || !reflect.DeepEqual(errs[0], want) {
+		t.Errorf("Load() with an unknown profile = %v, want %v", err, want)
+	}
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/goccy/go-json"
+	"github.com/tlipoca9/errors"
+)
+
+// field describes a leaf of Config, addressed by its dotted koanf key.
+type field struct {
+	Key   string
+	Index []int
+	Type  reflect.Type
+	Tag   reflect.StructTag
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// fields walks the json tags of t and returns all leaf fields in declaration order.
+func fields(t reflect.Type) []field {
+	var (
+		ret  []field
+		walk func(t reflect.Type, prefix string, index []int)
+	)
+	walk = func(t reflect.Type, prefix string, index []int) {
+		for i := range t.NumField() {
+			sf := t.Field(i)
+			if !sf.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(sf.Name)
+			}
+
+			key := name
+			if prefix != "" {
+				key = prefix + "." + name
+			}
+			idx := append(append([]int{}, index...), i)
+
+			if sf.Type.Kind() == reflect.Struct {
+				walk(sf.Type, key, idx)
+				continue
+			}
+			ret = append(ret, field{Key: key, Index: idx, Type: sf.Type, Tag: sf.Tag})
+		}
+	}
+	walk(t, "", nil)
+	return ret
+}
+
+var configFields = sync.OnceValue(func() []field {
+	return fields(reflect.TypeOf(Config{}))
+})
+
+func (f field) isDuration() bool {
+	return f.Type == durationType
+}
+
+func (f field) isStringMap() bool {
+	return f.Type.Kind() == reflect.Map &&
+		f.Type.Key().Kind() == reflect.String &&
+		f.Type.Elem().Kind() == reflect.String
+}
+
+func (f field) isStringSlice() bool {
+	return f.Type.Kind() == reflect.Slice && f.Type.Elem().Kind() == reflect.String
+}
+
+// parseStringMap parses "k1=v1,k2=v2" style values used by env vars and flags.
+func parseStringMap(items []string) map[string]any {
+	ret := make(map[string]any, len(items))
+	for _, item := range items {
+		for _, kv := range strings.Split(item, ",") {
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok || strings.TrimSpace(k) == "" {
+				continue
+			}
+			ret[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return ret
+}
+
+func (f field) isStructSlice() bool {
+	return f.Type.Kind() == reflect.Slice && f.Type.Elem().Kind() == reflect.Struct
+}
+
+// jsonToStructSliceHookFunc decodes the json strings set by env vars into
+// struct slices such as otel.sampler.rules.
+func jsonToStructSliceHookFunc() mapstructure.DecodeHookFuncType {
+	return func(from, to reflect.Type, data any) (any, error) {
+		if from.Kind() != reflect.String || to.Kind() != reflect.Slice || to.Elem().Kind() != reflect.Struct {
+			return data, nil
+		}
+		var ret []map[string]any
+		if err := json.Unmarshal([]byte(data.(string)), &ret); err != nil {
+			return nil, errors.Wrap(err, "expect a json array of objects")
+		}
+		return ret, nil
+	}
+}
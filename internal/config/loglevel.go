@@ -0,0 +1,115 @@
+package config
+
+import (
+	"log/slog"
+	"maps"
+	"net/netip"
+
+	"github.com/tlipoca9/errors"
+
+	"github.com/tlipoca9/asta/pkg/logx"
+)
+
+// LogLevels are the levels of the loggers, as served by /debug/loglevel.
+type LogLevels struct {
+	// Level is the level of the loggers without override.
+	Level string `json:"level"`
+	// Overrides are the levels of the named loggers and their children.
+	Overrides map[string]string `json:"overrides"`
+}
+
+// Logger returns a logger named name, whose level can be overridden by
+// service.log_levels and /debug/loglevel. Create it after Bootstrap.
+func Logger(name string) *slog.Logger {
+	h := logx.NewContextHandler(logx.NewLevelHandler(logLevels.Leveler(name), rootHandler))
+	return slog.New(h).With(slog.String("logger", name))
+}
+
+func GetLogLevels() LogLevels {
+	ret := LogLevels{Level: logLevels.Default().String(), Overrides: map[string]string{}}
+	for name, level := range logLevels.Overrides() {
+		ret.Overrides[name] = level.String()
+	}
+	return ret
+}
+
+// SetLogLevels changes the default level unless l.Level is empty, and the
+// levels of l.Overrides, where an empty level removes the override. Nothing
+// is changed if a level is invalid.
+func SetLogLevels(l LogLevels) error {
+	var level slog.Level
+	if l.Level != "" {
+		if err := level.UnmarshalText([]byte(l.Level)); err != nil {
+			return errors.Wrapf(err, "invalid level %q", l.Level)
+		}
+	}
+	set := make(map[string]slog.Level, len(l.Overrides))
+	var unset []string
+	for name, s := range l.Overrides {
+		if s == "" {
+			unset = append(unset, name)
+			continue
+		}
+		var v slog.Level
+		if err := v.UnmarshalText([]byte(s)); err != nil {
+			return errors.Wrapf(err, "invalid level %q of logger %q", s, name)
+		}
+		set[name] = v
+	}
+
+	if l.Level != "" {
+		logLevels.SetDefault(level)
+	}
+	logLevels.Update(func(overrides map[string]slog.Level) {
+		for _, name := range unset {
+			delete(overrides, name)
+		}
+		maps.Copy(overrides, set)
+	})
+	return nil
+}
+
+// TrustDebugLog reports whether a request from ip may enable its debug logs
+// with the service.debug_log.header value: either signed by the key, see
+// logx.SignDebugLog, or "1" from an allowed network.
+func TrustDebugLog(value, ip string) bool {
+	cfg := Current().Service.DebugLog
+	if cfg.Key != "" && logx.VerifyDebugLog([]byte(cfg.Key), value, cfg.MaxTTL) {
+		return true
+	}
+	if value != "1" {
+		return false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	for _, s := range cfg.AllowedCIDRs {
+		if prefix, err := parsePrefix(s); err == nil && prefix.Contains(addr.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+// toggleDebug switches the default level to debug, or back to info.
+func toggleDebug() slog.Level {
+	level := slog.LevelDebug
+	if logLevels.Default() <= slog.LevelDebug {
+		level = slog.LevelInfo
+	}
+	logLevels.SetDefault(level)
+	return level
+}
+
+// overridesOf parses service.log_levels, which have been validated.
+func overridesOf(cfg *Config) map[string]slog.Level {
+	ret := make(map[string]slog.Level, len(cfg.Service.LogLevels))
+	for name, s := range cfg.Service.LogLevels {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(s)); err == nil {
+			ret[name] = level
+		}
+	}
+	return ret
+}
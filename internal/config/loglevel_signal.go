@@ -0,0 +1,32 @@
+//go:build !windows
+
+package config
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchLogLevelSignal toggles debug logs on SIGUSR1, see toggleDebug.
+func watchLogLevelSignal() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ch:
+				level := toggleDebug()
+				log.Info("log level toggled", slog.String("level", level.String()))
+			case <-done:
+				return
+			}
+		}
+	}()
+	DeferShutdown("loglevel-signal", func() {
+		signal.Stop(ch)
+		close(done)
+	})
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tlipoca9/asta/pkg/logx"
+)
+
+func TestTrustDebugLog(t *testing.T) {
+	var cfg Config
+	cfg.Service.DebugLog.Key = "secret"
+	cfg.Service.DebugLog.MaxTTL = time.Hour
+	cfg.Service.DebugLog.AllowedCIDRs = []string{"10.0.0.0/8", "192.168.1.1"}
+	old := current.Load()
+	current.Store(&cfg)
+	t.Cleanup(func() { current.Store(old) })
+
+	signed := logx.SignDebugLog([]byte("secret"), time.Now().Add(time.Minute))
+	tests := []struct {
+		value, ip string
+		want      bool
+	}{
+		{signed, "203.0.113.1", true},
+		{logx.SignDebugLog([]byte("other"), time.Now().Add(time.Minute)), "203.0.113.1", false},
+		{logx.SignDebugLog([]byte("secret"), time.Now().Add(-time.Minute)), "203.0.113.1", false},
+		{logx.SignDebugLog([]byte("secret"), time.Now().Add(2*time.Hour)), "203.0.113.1", false},
+		{"1", "10.1.2.3", true},
+		{"1", "::ffff:10.1.2.3", true},
+		{"1", "192.168.1.1", true},
+		{"1", "192.168.1.2", false},
+		{"1", "203.0.113.1", false},
+		{"1", "not an ip", false},
+		{"true", "10.1.2.3", false},
+	}
+	for _, tt := range tests {
+		if got := TrustDebugLog(tt.value, tt.ip); got != tt.want {
+			t.Errorf("TrustDebugLog(%q, %q) = %v, want %v", tt.value, tt.ip, got, tt.want)
+		}
+	}
+
+	cfg.Service.DebugLog.Key = ""
+	if TrustDebugLog(signed, "203.0.113.1") {
+		t.Error("TrustDebugLog trusted a signed value without key")
+	}
+}
+
+func TestSetLogLevels(t *testing.T) {
+	old, oldOverrides := logLevels.Default(), logLevels.Overrides()
+	t.Cleanup(func() {
+		logLevels.SetDefault(old)
+		logLevels.SetOverrides(oldOverrides)
+	})
+	logLevels.SetDefault(slog.LevelInfo)
+	logLevels.SetOverrides(map[string]slog.Level{"database": slog.LevelWarn, "cache": slog.LevelDebug})
+
+	if err := SetLogLevels(LogLevels{Level: "debug", Overrides: map[string]string{"server": "loud"}}); err == nil {
+		t.Error("SetLogLevels accepted an invalid level")
+	}
+	if logLevels.Default() != slog.LevelInfo || len(logLevels.Overrides()) != 2 {
+		t.Error("SetLogLevels changed the levels despite an invalid one")
+	}
+
+	err := SetLogLevels(LogLevels{Overrides: map[string]string{"cache": "", "server": "error"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]slog.Level{"database": slog.LevelWarn, "server": slog.LevelError}
+	if got := logLevels.Overrides(); !maps.Equal(got, want) {
+		t.Errorf("overrides = %v, want %v", got, want)
+	}
+
+	// concurrent updates of different loggers must all be kept
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = SetLogLevels(LogLevels{Overrides: map[string]string{fmt.Sprint("logger", i): "debug"}})
+		}()
+	}
+	wg.Wait()
+	if got := len(logLevels.Overrides()); got != len(want)+50 {
+		t.Errorf("%d overrides after concurrent updates, want %d", got, len(want)+50)
+	}
+}
@@ -0,0 +1,4 @@
+package config
+
+// watchLogLevelSignal does nothing, there is no SIGUSR1 on windows.
+func watchLogLevelSignal() {}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	"github.com/tlipoca9/errors"
+	"go.opentelemetry.io/contrib/bridges/otelslog"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutlog"
+	"go.opentelemetry.io/otel/log/global"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+
+	"github.com/tlipoca9/asta/pkg/fiberx"
+	"github.com/tlipoca9/asta/pkg/logx"
+	"github.com/tlipoca9/asta/pkg/rotatex"
+)
+
+// initLogs exports the logs as otel log records along with the handler of
+// initLogger if otel.logs.enabled is set. The records are correlated with the
+// span of their context, so the trace_id and span_id attributes are dropped.
+func initLogs() error {
+	if !C.Otel.Logs.Enabled {
+		return nil
+	}
+
+	var exporter sdklog.Exporter
+	switch {
+	case C.Otel.CollectorEndpoint != "":
+		var err error
+		exporter, err = newOTLPLogExporter(context.Background())
+		if err != nil {
+			return err
+		}
+	default:
+		f := C.Otel.File
+		out, err := rotatex.New(C.Otel.Logs.Path, rotatex.Options{
+			MaxSize:  int64(f.MaxSizeMB) << 20,
+			Interval: f.RotateInterval,
+			MaxFiles: f.MaxFiles,
+			Compress: f.Compress,
+		})
+		if err != nil {
+			return errors.Wrap(err, "open otel log file failed")
+		}
+		// registered before the logger provider, so that it is closed after the last flush
+		DeferShutdown("otel-log-file", out.Close)
+		exporter, err = stdoutlog.New(stdoutlog.WithWriter(out))
+		if err != nil {
+			return errors.Wrap(err, "create log file exporter failed")
+		}
+	}
+
+	lp := sdklog.NewLoggerProvider(
+		sdklog.WithResource(Resource()),
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)),
+	)
+	global.SetLoggerProvider(lp)
+	DeferShutdown("logger-provider", lp.Shutdown)
+
+	var h slog.Handler = otelslog.NewHandler("github.com/tlipoca9/asta", otelslog.WithLoggerProvider(lp))
+	traceKeys := []string{fiberx.ContextKeyTraceID.String(), fiberx.ContextKeySpanID.String()}
+	h = logx.NewReplaceAttrHandler(h, func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 && slices.Contains(traceKeys, a.Key) {
+			return slog.Attr{}
+		}
+		return redactAttr(groups, a)
+	})
+	setLogger(logx.NewFanoutHandler(logHandler, h))
+	return nil
+}
+
+var (
+	logGRPCOptions = otlpOptionSet[otlploggrpc.Option]{
+		endpoint:       otlploggrpc.WithEndpoint,
+		endpointURL:    otlploggrpc.WithEndpointURL,
+		insecure:       otlploggrpc.WithInsecure,
+		tlsCredentials: otlploggrpc.WithTLSCredentials,
+		gzip:           otlploggrpc.WithCompressor(OtelCompressionGzip),
+		timeout:        otlploggrpc.WithTimeout,
+		headers:        otlploggrpc.WithHeaders,
+	}
+	logHTTPOptions = otlpOptionSet[otlploghttp.Option]{
+		endpoint:    otlploghttp.WithEndpoint,
+		endpointURL: otlploghttp.WithEndpointURL,
+		insecure:    otlploghttp.WithInsecure,
+		tlsConfig:   otlploghttp.WithTLSClientConfig,
+		gzip:        otlploghttp.WithCompression(otlploghttp.GzipCompression),
+		timeout:     otlploghttp.WithTimeout,
+		headers:     otlploghttp.WithHeaders,
+	}
+)
+
+// newOTLPLogExporter creates an OTLP log exporter for the collector of
+// newOTLPTraceExporter.
+func newOTLPLogExporter(ctx context.Context) (sdklog.Exporter, error) {
+	switch C.Otel.Protocol {
+	case "", OtelProtocolGRPC:
+		return newOTLPExporter(ctx, "logs", otlploggrpc.New, logGRPCOptions)
+	case OtelProtocolHTTPProtobuf:
+		return newOTLPExporter(ctx, "logs", otlploghttp.New, logHTTPOptions)
+	default:
+		return nil, errors.Newf("unknown otel protocol %q", C.Otel.Protocol)
+	}
+}
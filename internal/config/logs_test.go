@@ -0,0 +1,76 @@
+package config
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/log/global"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+)
+
+func TestInitLogs(t *testing.T) {
+	tests := []struct {
+		name             string
+		enabled, collect bool
+	}{
+		{"disabled", false, true},
+		{"file", true, false},
+		{"collector", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old, oldLP := C, global.GetLoggerProvider()
+			oldLog, oldDefault, oldLogHandler, oldRootHandler := log, slog.Default(), logHandler, rootHandler
+			t.Cleanup(func() {
+				C = old
+				if global.GetLoggerProvider() != oldLP {
+					global.SetLoggerProvider(oldLP)
+				}
+				log, logHandler, rootHandler = oldLog, oldLogHandler, oldRootHandler
+				slog.SetDefault(oldDefault)
+			})
+			logHandler = slog.NewTextHandler(io.Discard, nil)
+			setLogger(logHandler)
+
+			addr, conns := collector(t)
+			path := filepath.Join(t.TempDir(), "otel-log.log")
+			C.Otel.Logs.Enabled = tt.enabled
+			C.Otel.Logs.Path = path
+			if tt.collect {
+				C.Otel.CollectorEndpoint = addr
+			}
+			C.Otel.Protocol = OtelProtocolGRPC
+			C.Otel.Insecure = true
+			C.Otel.Timeout = time.Second
+			shutdown := shutdownDeferred(t)
+
+			if err := initLogs(); err != nil {
+				t.Fatal(err)
+			}
+			_, isSDK := global.GetLoggerProvider().(*sdklog.LoggerProvider)
+			if isSDK != tt.enabled {
+				t.Errorf("logger provider is %T", global.GetLoggerProvider())
+			}
+			log.InfoContext(context.Background(), "exported")
+			shutdown()
+
+			if got := connected(conns); got != (tt.enabled && tt.collect) {
+				t.Errorf("connected to the collector: %v, want %v", got, tt.enabled && tt.collect)
+			}
+			data, err := os.ReadFile(path)
+			wantFile := tt.enabled && !tt.collect
+			if (err == nil) != wantFile {
+				t.Fatalf("log file exists: %v, want %v", err == nil, wantFile)
+			}
+			if wantFile && !strings.Contains(string(data), "exported") {
+				t.Errorf("log file = %q, want the exported record", data)
+			}
+		})
+	}
+}
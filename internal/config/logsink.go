@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"time"
+
+	"github.com/lmittmann/tint"
+	"github.com/mattn/go-colorable"
+	"github.com/mattn/go-isatty"
+	"github.com/tlipoca9/errors"
+
+	"github.com/tlipoca9/asta/pkg/logx"
+	"github.com/tlipoca9/asta/pkg/rotatex"
+)
+
+const (
+	LogSinkStderr = "stderr"
+	LogSinkFile   = "file"
+	LogSinkSyslog = "syslog"
+
+	LogFormatConsole = "console"
+	LogFormatJSON    = "json"
+)
+
+// logSinkClosers close the files and connections of the log sinks, see
+// closeLogSinks.
+var logSinkClosers []func() error
+
+// newLogSink creates the handler of a log sink. Its file or connection is
+// closed by closeLogSinks.
+func newLogSink(sink LogSink) (slog.Handler, error) {
+	format := sink.Format
+	if format == "" {
+		format = LogFormatJSON
+		if C.Service.Console {
+			format = LogFormatConsole
+		}
+	}
+
+	var h slog.Handler
+	switch sink.Type {
+	case LogSinkStderr:
+		color := isatty.IsTerminal(os.Stderr.Fd()) || isatty.IsCygwinTerminal(os.Stderr.Fd())
+		h = newLogFormatHandler(format, colorable.NewColorableStderr(), color)
+	case LogSinkFile:
+		if sink.Path == "" {
+			return nil, errors.New("path is required by file sinks")
+		}
+		out, err := rotatex.New(sink.Path, rotatex.Options{
+			MaxSize:  int64(sink.MaxSizeMB) << 20,
+			Interval: sink.RotateInterval,
+			MaxFiles: sink.MaxFiles,
+			Compress: sink.Compress,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "open log file failed")
+		}
+		logSinkClosers = append(logSinkClosers, out.Close)
+		h = newLogFormatHandler(format, out, false)
+	case LogSinkSyslog:
+		if sink.Network == "" || sink.Address == "" {
+			return nil, errors.New("network and address are required by syslog sinks")
+		}
+		facility, ok := logx.SyslogFacilities[sink.Facility]
+		if !ok {
+			facility = logx.SyslogFacilities["user"]
+		}
+		tag := sink.Tag
+		if tag == "" {
+			tag = C.Service.Name
+		}
+		sh, err := logx.NewSyslogHandler(sink.Network, sink.Address, facility, tag, func(w io.Writer) slog.Handler {
+			return newLogFormatHandler(format, w, false)
+		})
+		if err != nil {
+			return nil, err
+		}
+		logSinkClosers = append(logSinkClosers, sh.Close)
+		h = sh
+	default:
+		return nil, errors.Newf("unknown log sink type %q", sink.Type)
+	}
+
+	if sink.Level != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(sink.Level)); err != nil {
+			return nil, errors.Wrapf(err, "invalid level %q", sink.Level)
+		}
+		h = logx.NewLevelHandler(level, h)
+	}
+	return h, nil
+}
+
+// newLogFormatHandler writes the records to w in format, the console format
+// is colored if color is set.
+func newLogFormatHandler(format string, w io.Writer, color bool) slog.Handler {
+	// the levels are checked by the loggers and sinks, see setLogger
+	levelAll := slog.Level(math.MinInt)
+	if format == LogFormatConsole {
+		return tint.NewHandler(w, &tint.Options{
+			Level:       levelAll,
+			TimeFormat:  time.TimeOnly,
+			ReplaceAttr: replaceAttr,
+			NoColor:     !color,
+		})
+	}
+	return slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       levelAll,
+		ReplaceAttr: replaceAttr,
+	})
+}
+
+// closeLogSinks closes the log sinks once the shutdown is complete, so that
+// they receive the logs of the shutdown too. Errors go to stderr as there is
+// no logger left.
+func closeLogSinks() {
+	for _, c := range logSinkClosers {
+		if err := c(); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, "close log sink failed:", err)
+		}
+	}
+	logSinkClosers = nil
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"context"
+
+	"github.com/tlipoca9/errors"
+	"go.opentelemetry.io/contrib/instrumentation/runtime"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	otelprom "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/sdk/metric"
+)
+
+// MetricsNamespace prefixes the names of the prometheus metrics of the service.
+const MetricsNamespace = "asta"
+
+// initMeter sets up the global meter provider. Its metrics are exposed along
+// with the prometheus ones on /metrics, and pushed to the collector if
+// otel.metrics.otlp is set.
+func initMeter() error {
+	promExporter, err := otelprom.New()
+	if err != nil {
+		return errors.Wrap(err, "create prometheus metric exporter failed")
+	}
+	opts := []metric.Option{
+		metric.WithResource(Resource()),
+		metric.WithReader(promExporter),
+	}
+	if C.Otel.Metrics.OTLP && C.Otel.CollectorEndpoint != "" {
+		exporter, err := newOTLPMetricExporter(context.Background())
+		if err != nil {
+			return err
+		}
+		opts = append(opts, metric.WithReader(metric.NewPeriodicReader(exporter,
+			metric.WithInterval(C.Otel.Metrics.Interval),
+		)))
+	}
+
+	mp := metric.NewMeterProvider(opts...)
+	otel.SetMeterProvider(mp)
+	DeferShutdown("meter-provider", mp.Shutdown)
+
+	if C.Otel.Metrics.Runtime {
+		if err := runtime.Start(runtime.WithMeterProvider(mp)); err != nil {
+			return errors.Wrap(err, "start runtime metrics failed")
+		}
+	}
+	return nil
+}
+
+var (
+	metricGRPCOptions = otlpOptionSet[otlpmetricgrpc.Option]{
+		endpoint:       otlpmetricgrpc.WithEndpoint,
+		endpointURL:    otlpmetricgrpc.WithEndpointURL,
+		insecure:       otlpmetricgrpc.WithInsecure,
+		tlsCredentials: otlpmetricgrpc.WithTLSCredentials,
+		gzip:           otlpmetricgrpc.WithCompressor(OtelCompressionGzip),
+		timeout:        otlpmetricgrpc.WithTimeout,
+		headers:        otlpmetricgrpc.WithHeaders,
+	}
+	metricHTTPOptions = otlpOptionSet[otlpmetrichttp.Option]{
+		endpoint:    otlpmetrichttp.WithEndpoint,
+		endpointURL: otlpmetrichttp.WithEndpointURL,
+		insecure:    otlpmetrichttp.WithInsecure,
+		tlsConfig:   otlpmetrichttp.WithTLSClientConfig,
+		gzip:        otlpmetrichttp.WithCompression(otlpmetrichttp.GzipCompression),
+		timeout:     otlpmetrichttp.WithTimeout,
+		headers:     otlpmetrichttp.WithHeaders,
+	}
+)
+
+// newOTLPMetricExporter creates an OTLP metric exporter for the collector of
+// newOTLPTraceExporter.
+func newOTLPMetricExporter(ctx context.Context) (metric.Exporter, error) {
+	switch C.Otel.Protocol {
+	case "", OtelProtocolGRPC:
+		return newOTLPExporter(ctx, "metrics", otlpmetricgrpc.New, metricGRPCOptions)
+	case OtelProtocolHTTPProtobuf:
+		return newOTLPExporter(ctx, "metrics", otlpmetrichttp.New, metricHTTPOptions)
+	default:
+		return nil, errors.Newf("unknown otel protocol %q", C.Otel.Protocol)
+	}
+}
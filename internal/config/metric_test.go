@@ -0,0 +1,106 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/metric"
+)
+
+// collector accepts connections on a local address, reporting each of them on
+// the returned channel. It does not speak otlp, the exports fail.
+func collector(t *testing.T) (string, <-chan struct{}) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = lis.Close() })
+	conns := make(chan struct{}, 10)
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			conns <- struct{}{}
+			_ = conn.Close()
+		}
+	}()
+	return lis.Addr().String(), conns
+}
+
+// connected reports whether the collector got a connection within a second.
+func connected(conns <-chan struct{}) bool {
+	select {
+	case <-conns:
+		return true
+	case <-time.After(time.Second):
+		return false
+	}
+}
+
+// shutdownDeferred returns a function running the shutdowns deferred since,
+// which also runs at the end of the test.
+func shutdownDeferred(t *testing.T) func() {
+	n := defaultShutdownManager.Len()
+	var once sync.Once
+	shutdown := func() {
+		once.Do(func() {
+			m := &defaultShutdownManager
+			m.mux.Lock()
+			added := m.shutdowns[n:]
+			m.shutdowns = m.shutdowns[:n]
+			m.mux.Unlock()
+			for i := len(added) - 1; i >= 0; i-- {
+				_ = added[i].Shutdown(context.Background())
+			}
+		})
+	}
+	t.Cleanup(shutdown)
+	return shutdown
+}
+
+func TestInitMeter(t *testing.T) {
+	for _, otlp := range []bool{false, true} {
+		t.Run(fmt.Sprintf("otlp %v", otlp), func(t *testing.T) {
+			old, oldMP := C, otel.GetMeterProvider()
+			t.Cleanup(func() {
+				C = old
+				otel.SetMeterProvider(oldMP)
+			})
+			addr, conns := collector(t)
+			C.Otel.CollectorEndpoint = addr
+			C.Otel.Protocol = OtelProtocolGRPC
+			C.Otel.Insecure = true
+			C.Otel.Timeout = time.Second
+			C.Otel.Metrics.OTLP = otlp
+			C.Otel.Metrics.Interval = time.Hour
+			shutdown := shutdownDeferred(t)
+
+			if err := initMeter(); err != nil {
+				t.Fatal(err)
+			}
+			mp, ok := otel.GetMeterProvider().(*metric.MeterProvider)
+			if !ok {
+				t.Fatalf("meter provider is %T, want the sdk one", otel.GetMeterProvider())
+			}
+			counter, err := mp.Meter("test").Int64Counter("test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			counter.Add(context.Background(), 1)
+			_ = mp.ForceFlush(context.Background())
+			shutdown()
+
+			if got := connected(conns); got != otlp {
+				t.Errorf("connected to the collector: %v, want %v", got, otlp)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package config
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tlipoca9/errors"
+	"go.opentelemetry.io/contrib/propagators/aws/xray"
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/contrib/propagators/jaeger"
+	"go.opentelemetry.io/contrib/propagators/ot"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/tlipoca9/asta/pkg/otelx"
+)
+
+const (
+	OtelProtocolGRPC         = "grpc"
+	OtelProtocolHTTPProtobuf = "http/protobuf"
+
+	OtelCompressionGzip = "gzip"
+	OtelCompressionNone = "none"
+
+	PropagatorTraceContext = "tracecontext"
+	PropagatorBaggage      = "baggage"
+	PropagatorB3           = "b3"
+	PropagatorB3Multi      = "b3multi"
+	PropagatorJaeger       = "jaeger"
+	PropagatorXRay         = "xray"
+	PropagatorOT           = "ot"
+
+	SamplerAlways      = "always"
+	SamplerNever       = "never"
+	SamplerRatio       = "ratio"
+	SamplerParentRatio = "parent_ratio"
+	SamplerRules       = "rules"
+	SamplerRateLimited = "rate_limited"
+)
+
+var (
+	traceGRPCOptions = otlpOptionSet[otlptracegrpc.Option]{
+		endpoint:       otlptracegrpc.WithEndpoint,
+		endpointURL:    otlptracegrpc.WithEndpointURL,
+		insecure:       otlptracegrpc.WithInsecure,
+		tlsCredentials: otlptracegrpc.WithTLSCredentials,
+		gzip:           otlptracegrpc.WithCompressor(OtelCompressionGzip),
+		timeout:        otlptracegrpc.WithTimeout,
+		headers:        otlptracegrpc.WithHeaders,
+	}
+	traceHTTPOptions = otlpOptionSet[otlptracehttp.Option]{
+		endpoint:    otlptracehttp.WithEndpoint,
+		endpointURL: otlptracehttp.WithEndpointURL,
+		insecure:    otlptracehttp.WithInsecure,
+		tlsConfig:   otlptracehttp.WithTLSClientConfig,
+		gzip:        otlptracehttp.WithCompression(otlptracehttp.GzipCompression),
+		timeout:     otlptracehttp.WithTimeout,
+		headers:     otlptracehttp.WithHeaders,
+	}
+)
+
+// newOTLPTraceExporter creates an OTLP span exporter for the configured
+// collector endpoint. The endpoint is either "host:port" or a URL such as
+// "https://collector:4318", see otlpSignalURL.
+func newOTLPTraceExporter(ctx context.Context) (trace.SpanExporter, error) {
+	switch C.Otel.Protocol {
+	case "", OtelProtocolGRPC:
+		return newOTLPExporter(ctx, "traces", otlptracegrpc.New, traceGRPCOptions)
+	case OtelProtocolHTTPProtobuf:
+		return newOTLPExporter(ctx, "traces", otlptracehttp.New, traceHTTPOptions)
+	default:
+		return nil, errors.Newf("unknown otel protocol %q", C.Otel.Protocol)
+	}
+}
+
+// otlpOptionSet holds the option functions of an OTLP exporter package. They
+// have the same shape for every signal and protocol, so that newOTLPExporter
+// resolves the otel settings once for all of them.
+type otlpOptionSet[O any] struct {
+	endpoint       func(string) O
+	endpointURL    func(string) O
+	insecure       func() O
+	tlsConfig      func(*tls.Config) O                      // http only
+	tlsCredentials func(credentials.TransportCredentials) O // grpc only
+	gzip           O
+	timeout        func(time.Duration) O
+	headers        func(map[string]string) O
+}
+
+// newOTLPExporter creates the exporter of signal with newExporter, configured
+// by otel.collector_endpoint, otel.insecure, otel.tls, otel.compression,
+// otel.timeout and otel.headers.
+func newOTLPExporter[O, E any](
+	ctx context.Context,
+	signal string,
+	newExporter func(context.Context, ...O) (E, error),
+	set otlpOptionSet[O],
+) (E, error) {
+	tlsConfig, err := otlpTLSConfig()
+	if err != nil {
+		var zero E
+		return zero, err
+	}
+
+	protocol := cmp.Or(C.Otel.Protocol, OtelProtocolGRPC)
+	opts := []O{set.headers(C.Otel.Headers)}
+	switch {
+	case !strings.Contains(C.Otel.CollectorEndpoint, "://"):
+		opts = append(opts, set.endpoint(C.Otel.CollectorEndpoint))
+	case protocol == OtelProtocolHTTPProtobuf:
+		opts = append(opts, set.endpointURL(otlpSignalURL(C.Otel.CollectorEndpoint, signal)))
+	default:
+		opts = append(opts, set.endpointURL(C.Otel.CollectorEndpoint))
+	}
+	switch {
+	case C.Otel.Insecure:
+		opts = append(opts, set.insecure())
+	case tlsConfig != nil && set.tlsCredentials != nil:
+		opts = append(opts, set.tlsCredentials(credentials.NewTLS(tlsConfig)))
+	case tlsConfig != nil:
+		opts = append(opts, set.tlsConfig(tlsConfig))
+	}
+	if C.Otel.Compression == OtelCompressionGzip {
+		opts = append(opts, set.gzip)
+	}
+	if C.Otel.Timeout > 0 {
+		opts = append(opts, set.timeout(C.Otel.Timeout))
+	}
+	exporter, err := newExporter(ctx, opts...)
+	return exporter, errors.Wrapf(err, "create otlp %s %s exporter failed", protocol, signal)
+}
+
+// otlpSignalURL returns the http endpoint URL of a signal. A URL without path,
+// such as "https://collector:4318", gets the default "/v1/<signal>" path, and
+// the path of one given for traces, ending with "/v1/traces", is pointed to
+// the other signals.
+func otlpSignalURL(endpoint, signal string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		// the exporter reports the invalid URL
+		return endpoint
+	}
+	switch {
+	case u.Path == "" || u.Path == "/":
+		u.Path = "/v1/" + signal
+	case strings.HasSuffix(u.Path, "/v1/traces"):
+		u.Path = strings.TrimSuffix(u.Path, "/v1/traces") + "/v1/" + signal
+	}
+	return u.String()
+}
+
+// otlpTLSConfig returns nil when no TLS option is set, so that the exporter
+// falls back to the system defaults.
+func otlpTLSConfig() (*tls.Config, error) {
+	t := C.Otel.TLS
+	if t.CAFile == "" && t.CertFile == "" && t.KeyFile == "" && t.ServerName == "" && !t.InsecureSkipVerify {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify, //nolint:gosec // explicitly enabled by config
+	}
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "read otel ca file failed")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Newf("no certificates found in %s", t.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "load otel client certificate failed")
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+func batchOptions() []trace.BatchSpanProcessorOption {
+	var opts []trace.BatchSpanProcessorOption
+	b := C.Otel.Batch
+	if b.MaxQueueSize > 0 {
+		opts = append(opts, trace.WithMaxQueueSize(b.MaxQueueSize))
+	}
+	if b.MaxExportBatchSize > 0 {
+		opts = append(opts, trace.WithMaxExportBatchSize(b.MaxExportBatchSize))
+	}
+	if b.BatchTimeout > 0 {
+		opts = append(opts, trace.WithBatchTimeout(b.BatchTimeout))
+	}
+	if b.ExportTimeout > 0 {
+		opts = append(opts, trace.WithExportTimeout(b.ExportTimeout))
+	}
+	return opts
+}
+
+// newSampler creates the sampler of otel.sampler. Only root spans are sampled
+// by ratio and rate_limited, child spans follow their parent. The rules are
+// checked before the parent, so that e.g. a path can be dropped even if the
+// caller sampled it.
+func newSampler() trace.Sampler {
+	cfg := C.Otel.Sampler
+	var sampler trace.Sampler
+	switch cfg.Type {
+	case SamplerAlways:
+		sampler = trace.AlwaysSample()
+	case SamplerNever:
+		sampler = trace.NeverSample()
+	case SamplerRatio:
+		sampler = trace.TraceIDRatioBased(cfg.Ratio)
+	case SamplerRules:
+		rules := make([]otelx.Rule, 0, len(cfg.Rules))
+		for _, r := range cfg.Rules {
+			rule := otelx.Rule{Route: r.Route, Sampler: trace.AlwaysSample()}
+			switch r.Sample {
+			case SamplerNever:
+				rule.Sampler = trace.NeverSample()
+			case SamplerRatio:
+				rule.Sampler = trace.TraceIDRatioBased(r.Ratio)
+			}
+			rules = append(rules, rule)
+		}
+		sampler = otelx.RuleSampler(rules, trace.ParentBased(trace.TraceIDRatioBased(cfg.Ratio)))
+	case SamplerRateLimited:
+		sampler = trace.ParentBased(otelx.RateLimitedSampler(cfg.RateLimit))
+	default:
+		sampler = trace.ParentBased(trace.TraceIDRatioBased(cfg.Ratio))
+	}
+	if cfg.AlwaysSampleErrors {
+		sampler = otelx.RecordDroppedSampler(sampler)
+	}
+	return sampler
+}
+
+// newSpanProcessor batches the spans to exporter. With
+// otel.sampler.always_sample_errors, the failed spans of unsampled traces are
+// exported as well.
+func newSpanProcessor(exporter trace.SpanExporter) trace.SpanProcessor {
+	var sp trace.SpanProcessor = trace.NewBatchSpanProcessor(exporter, batchOptions()...)
+	if C.Otel.Sampler.AlwaysSampleErrors {
+		sp = otelx.NewErrorSpanProcessor(sp)
+	}
+	return sp
+}
+
+// newPropagator composes the otel.propagators, so that incoming requests are
+// continued from any of their headers and outgoing ones carry all of them.
+func newPropagator() propagation.TextMapPropagator {
+	var ps []propagation.TextMapPropagator
+	for _, name := range C.Otel.Propagators {
+		switch name {
+		case PropagatorTraceContext:
+			ps = append(ps, propagation.TraceContext{})
+		case PropagatorBaggage:
+			ps = append(ps, propagation.Baggage{})
+		case PropagatorB3:
+			ps = append(ps, b3.New())
+		case PropagatorB3Multi:
+			ps = append(ps, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		case PropagatorJaeger:
+			ps = append(ps, jaeger.Jaeger{})
+		case PropagatorXRay:
+			ps = append(ps, xray.Propagator{})
+		case PropagatorOT:
+			ps = append(ps, ot.OT{})
+		}
+	}
+	return propagation.NewCompositeTextMapPropagator(ps...)
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestOTLPSignalURL(t *testing.T) {
+	tests := []struct {
+		endpoint, signal, want string
+	}{
+		{"http://collector:4318", "traces", "http://collector:4318/v1/traces"},
+		{"http://collector:4318/", "metrics", "http://collector:4318/v1/metrics"},
+		{"https://collector:4318/v1/traces", "logs", "https://collector:4318/v1/logs"},
+		{"https://gateway/otlp/v1/traces", "metrics", "https://gateway/otlp/v1/metrics"},
+		{"https://gateway/custom", "logs", "https://gateway/custom"},
+	}
+	for _, tt := range tests {
+		if got := otlpSignalURL(tt.endpoint, tt.signal); got != tt.want {
+			t.Errorf("otlpSignalURL(%q, %q) = %q, want %q", tt.endpoint, tt.signal, got, tt.want)
+		}
+	}
+}
+
+type traceReceiver struct {
+	coltracepb.UnimplementedTraceServiceServer
+	spans chan string
+}
+
+func (r *traceReceiver) Export(
+	_ context.Context,
+	req *coltracepb.ExportTraceServiceRequest,
+) (*coltracepb.ExportTraceServiceResponse, error) {
+	for _, rs := range req.GetResourceSpans() {
+		for _, ss := range rs.GetScopeSpans() {
+			for _, s := range ss.GetSpans() {
+				r.spans <- s.GetName()
+			}
+		}
+	}
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+func TestOTLPTraceExporter(t *testing.T) {
+	receiver := &traceReceiver{spans: make(chan string, 10)}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	coltracepb.RegisterTraceServiceServer(srv, receiver)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	httpSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			http.NotFound(w, r)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req coltracepb.ExportTraceServiceRequest
+		if err := proto.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_, _ = receiver.Export(r.Context(), &req)
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		b, _ := proto.Marshal(&coltracepb.ExportTraceServiceResponse{})
+		_, _ = w.Write(b)
+	}))
+	t.Cleanup(httpSrv.Close)
+
+	tests := []struct {
+		name     string
+		protocol string
+		endpoint string
+	}{
+		{"grpc host:port", OtelProtocolGRPC, lis.Addr().String()},
+		{"grpc url", OtelProtocolGRPC, "http://" + lis.Addr().String()},
+		{"http base url", OtelProtocolHTTPProtobuf, httpSrv.URL},
+		{"http traces url", OtelProtocolHTTPProtobuf, httpSrv.URL + "/v1/traces"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := C
+			t.Cleanup(func() { C = old })
+			C.Otel.Protocol = tt.protocol
+			C.Otel.CollectorEndpoint = tt.endpoint
+			C.Otel.Insecure = true
+			C.Otel.Timeout = 5 * time.Second
+
+			ctx := context.Background()
+			exporter, err := newOTLPTraceExporter(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tp := trace.NewTracerProvider(trace.WithSyncer(exporter))
+			_, span := tp.Tracer("test").Start(ctx, tt.name)
+			span.End()
+			if err := tp.Shutdown(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			select {
+			case name := <-receiver.spans:
+				if name != tt.name {
+					t.Errorf("received span %q, want %q", name, tt.name)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("no span received")
+			}
+		})
+	}
+}
+
+func TestPropagators(t *testing.T) {
+	old := C.Otel.Propagators
+	t.Cleanup(func() { C.Otel.Propagators = old })
+
+	sc := oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    oteltrace.TraceID{0x5f, 0x1e, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+		SpanID:     oteltrace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceFlags: oteltrace.FlagsSampled,
+	})
+	member, _ := baggage.NewMember("user", "42")
+	bag, _ := baggage.New(member)
+	ctx := baggage.ContextWithBaggage(oteltrace.ContextWithSpanContext(context.Background(), sc), bag)
+
+	tests := []struct {
+		name, header string
+	}{
+		{PropagatorTraceContext, "traceparent"},
+		{PropagatorBaggage, "baggage"},
+		{PropagatorB3, "b3"},
+		{PropagatorB3Multi, "x-b3-traceid"},
+		{PropagatorJaeger, "uber-trace-id"},
+		{PropagatorXRay, "X-Amzn-Trace-Id"},
+		{PropagatorOT, "ot-tracer-traceid"},
+	}
+	for _, tt := range tests {
+		C.Otel.Propagators = []string{tt.name}
+		p := newPropagator()
+		carrier := propagation.MapCarrier{}
+		p.Inject(ctx, carrier)
+		if carrier.Get(tt.header) == "" {
+			t.Errorf("%s: injected %v, want the %s header", tt.name, carrier, tt.header)
+			continue
+		}
+
+		got := p.Extract(context.Background(), carrier)
+		if tt.name == PropagatorBaggage {
+			if v := baggage.FromContext(got).Member("user").Value(); v != "42" {
+				t.Errorf("%s: extracted baggage user = %q, want 42", tt.name, v)
+			}
+			continue
+		}
+		traceID := sc.TraceID()
+		if tt.name == PropagatorOT {
+			// ot only carries the low 64 bits of the trace id
+			clear(traceID[:8])
+		}
+		gotSC := oteltrace.SpanContextFromContext(got)
+		if gotSC.TraceID() != traceID || gotSC.SpanID() != sc.SpanID() || !gotSC.IsSampled() || !gotSC.IsRemote() {
+			t.Errorf("%s: extracted %v, want the remote %v", tt.name, gotSC, sc)
+		}
+	}
+
+	t.Setenv("ASTA_DATABASE_DB_NAME", "asta")
+	t.Setenv("ASTA_DATABASE_USERNAME", "asta")
+	t.Setenv("ASTA_OTEL_PROPAGATORS", "tracecontext,w3c")
+	_, _, err := Load(Options{})
+	errs, ok := err.(ValidationError)
+	if !ok || len(errs) != 1 || errs[0].Key != "otel.propagators" {
+		t.Errorf("Load() with an unknown propagator = %v, want an otel.propagators error", err)
+	}
+}
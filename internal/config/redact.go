@@ -0,0 +1,96 @@
+package config
+
+import (
+	"log/slog"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const Redacted = "******"
+
+// Redact converts v, a struct or a pointer to struct, into a map keyed by json
+// tags where the values of fields tagged `secret:"true"` are masked. Anything
+// that prints the config must go through Redact.
+func Redact(v any) any {
+	return redactValue(reflect.ValueOf(v), false)
+}
+
+func redactValue(v reflect.Value, secret bool) any {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Type() == durationType:
+		return time.Duration(v.Int()).String()
+	case v.Kind() == reflect.Struct:
+		ret := make(map[string]any, v.NumField())
+		for i := range v.NumField() {
+			sf := v.Type().Field(i)
+			if !sf.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(sf.Name)
+			}
+			ret[name] = redactValue(v.Field(i), sf.Tag.Get("secret") == "true")
+		}
+		return ret
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		ret := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			ret[iter.Key().String()] = redactValue(iter.Value(), secret)
+		}
+		return ret
+	case secret:
+		if v.IsZero() {
+			return v.Interface()
+		}
+		return Redacted
+	default:
+		return v.Interface()
+	}
+}
+
+// secretKeys contains the full keys, e.g. "database.password", of all secret
+// config fields.
+var secretKeys = func() map[string]struct{} {
+	ret := make(map[string]struct{})
+	for _, f := range configFields() {
+		if f.Tag.Get("secret") == "true" {
+			ret[f.Key] = struct{}{}
+		}
+	}
+	return ret
+}()
+
+// redactAttr masks log attributes which carry the config, or a secret field
+// under its full key, either as attribute key ("database.password") or as
+// group path and key (slog.Group("database", "password", ...)).
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	switch v := a.Value.Any().(type) {
+	case Config, *Config:
+		return slog.Any(a.Key, Redact(v))
+	}
+
+	key := a.Key
+	if len(groups) > 0 {
+		key = strings.Join(groups, ".") + "." + a.Key
+	}
+	if _, ok := secretKeys[key]; ok {
+		return slog.String(a.Key, Redacted)
+	}
+	return a
+}
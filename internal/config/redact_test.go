@@ -0,0 +1,31 @@
+package config
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func TestRedactAttr(t *testing.T) {
+	tests := []struct {
+		groups []string
+		attr   slog.Attr
+		want   string
+	}{
+		{nil, slog.String("database.password", "p"), Redacted},
+		{[]string{"database"}, slog.String("password", "p"), Redacted},
+		{[]string{"service", "debug_log"}, slog.String("key", "k"), Redacted},
+		{nil, slog.String("password", "p"), "p"},
+		{nil, slog.String("key", "user:42"), "user:42"},
+		{nil, slog.String("headers", "x"), "x"},
+		{[]string{"cache"}, slog.String("key", "user:42"), "user:42"},
+	}
+	for _, tt := range tests {
+		if got := redactAttr(tt.groups, tt.attr).Value.String(); got != tt.want {
+			t.Errorf("redactAttr(%v, %v) = %q, want %q", tt.groups, tt.attr, got, tt.want)
+		}
+	}
+
+	if _, ok := redactAttr(nil, slog.Any("config", &Config{})).Value.Any().(map[string]any); !ok {
+		t.Error("redactAttr did not redact the config")
+	}
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/tlipoca9/errors"
+)
+
+// reloadDelay debounces the bursts of events emitted by editors on save.
+const reloadDelay = 200 * time.Millisecond
+
+// Subscriber is notified after the live config has been replaced.
+type Subscriber func(old, cfg *Config)
+
+var (
+	reloadMux   sync.Mutex
+	subscribers []namedSubscriber
+)
+
+type namedSubscriber struct {
+	name string
+	fn   Subscriber
+}
+
+// Subscribe registers fn to be called on every successful reload.
+func Subscribe(name string, fn Subscriber) {
+	reloadMux.Lock()
+	defer reloadMux.Unlock()
+	subscribers = append(subscribers, namedSubscriber{name: name, fn: fn})
+}
+
+// Reload loads the config again from the same sources as Bootstrap. Invalid configs
+// are rejected as a whole. Fields which are not tagged `live:"true"` keep their
+// current value and are reported as requiring a restart.
+func Reload() error {
+	reloadMux.Lock()
+	defer reloadMux.Unlock()
+
+	cfg, _, err := Load(loadOpts)
+	if err != nil {
+		return errors.Wrap(err, "reload config failed")
+	}
+
+	old := current.Load()
+	var changed, restart []string
+	for _, f := range configFields() {
+		oldValue := reflect.ValueOf(old).Elem().FieldByIndex(f.Index)
+		newValue := reflect.ValueOf(cfg).Elem().FieldByIndex(f.Index)
+		if reflect.DeepEqual(oldValue.Interface(), newValue.Interface()) {
+			continue
+		}
+		if f.Tag.Get("live") != "true" {
+			restart = append(restart, f.Key)
+			newValue.Set(oldValue)
+			continue
+		}
+		changed = append(changed, f.Key)
+	}
+	if len(restart) > 0 {
+		log.Warn("config changes require restart", slog.Any("keys", restart))
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	current.Store(cfg)
+	for _, s := range subscribers {
+		s.fn(old, cfg)
+		log.Debug("config subscriber notified", "name", s.name)
+	}
+	log.Info("config reloaded", slog.Any("keys", changed))
+	return nil
+}
+
+// watch reloads the config when one of the config files changes or the
+// process receives SIGHUP.
+func watch() error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return errors.Wrap(err, "create config watcher failed")
+	}
+
+	files := make(map[string]struct{})
+	for _, path := range configFiles(loadOpts) {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return errors.Wrapf(err, "resolve config file %s failed", path)
+		}
+		files[abs] = struct{}{}
+		// watch the directory so that files replaced by editors are picked up
+		if err := w.Add(filepath.Dir(abs)); err != nil {
+			return errors.Wrapf(err, "watch config file %s failed", path)
+		}
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	done := make(chan struct{})
+
+	reload := func() {
+		if err := Reload(); err != nil {
+			log.Error("reload config failed", "error", err)
+		}
+	}
+	go func() {
+		var timer *time.Timer
+		for {
+			select {
+			case <-done:
+				return
+			case <-hup:
+				log.Info("catch reload signal", slog.String("signal", syscall.SIGHUP.String()))
+				reload()
+			case event, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if _, ok := files[filepath.Clean(event.Name)]; !ok {
+					continue
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(reloadDelay, reload)
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				log.Error("watch config failed", "error", err)
+			}
+		}
+	}()
+
+	DeferShutdown("config-watcher", func() error {
+		signal.Stop(hup)
+		close(done)
+		return w.Close()
+	})
+	return nil
+}
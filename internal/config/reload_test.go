@@ -0,0 +1,79 @@
+package config
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "config.toml", required+`
+[service]
+addr = ":9000"
+`)
+
+	oldOpts, oldCurrent, oldSubscribers, oldLog := loadOpts, current.Load(), subscribers, log
+	t.Cleanup(func() {
+		loadOpts, subscribers, log = oldOpts, oldSubscribers, oldLog
+		current.Store(oldCurrent)
+	})
+	var logs bytes.Buffer
+	log = slog.New(slog.NewTextHandler(&logs, nil))
+	subscribers = nil
+
+	loadOpts = Options{Files: []string{path}, SkipEnv: true}
+	cfg, _, err := Load(loadOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current.Store(cfg)
+	var notified []*Config
+	Subscribe("test", func(old, cfg *Config) {
+		if old.Service.Debug || !cfg.Service.Debug {
+			t.Errorf("subscriber got debug %v => %v, want false => true", old.Service.Debug, cfg.Service.Debug)
+		}
+		notified = append(notified, cfg)
+	})
+
+	// service.debug is live, service.addr is not
+	writeFile(t, dir, "config.toml", required+`
+[service]
+addr = ":9001"
+debug = true
+`)
+	if err := Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := Current(); !got.Service.Debug || got.Service.Addr != ":9000" {
+		t.Errorf("Current() debug %v addr %q, want true :9000", got.Service.Debug, got.Service.Addr)
+	}
+	if len(notified) != 1 || notified[0] != Current() {
+		t.Errorf("subscriber notified %d times, want once with the current config", len(notified))
+	}
+	if out := logs.String(); !strings.Contains(out, "config changes require restart") ||
+		!strings.Contains(out, "service.addr") {
+		t.Errorf("logs = %q, want service.addr reported as requiring a restart", out)
+	}
+
+	// invalid configs are rejected as a whole
+	reloaded := Current()
+	writeFile(t, dir, "config.toml", required+`
+[service]
+addr = ":9000"
+debug = false
+
+[otel]
+protocol = "udp"
+`)
+	if err := Reload(); err == nil {
+		t.Error("Reload() of an invalid config succeeded")
+	}
+	if Current() != reloaded || !Current().Service.Debug {
+		t.Error("Reload() of an invalid config replaced the current config")
+	}
+	if len(notified) != 1 {
+		t.Errorf("subscriber notified %d times, want once", len(notified))
+	}
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"context"
+
+	"github.com/tlipoca9/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"github.com/tlipoca9/asta/internal/buildinfo"
+)
+
+const (
+	ResourceDetectorEnv       = "env"
+	ResourceDetectorHost      = "host"
+	ResourceDetectorOS        = "os"
+	ResourceDetectorProcess   = "process"
+	ResourceDetectorContainer = "container"
+)
+
+var otelResource = resource.Default()
+
+// Resource returns the otel resource describing this process, shared by the
+// tracer, meter and logger providers. It is set up by Bootstrap.
+func Resource() *resource.Resource {
+	return otelResource
+}
+
+// newResource merges the attributes found by the otel.resource.detectors with
+// the service attributes and the custom otel.resource.attributes, the latter
+// taking precedence. If some detectors fail, the resource is returned along
+// with an error wrapping resource.ErrPartialResource.
+func newResource(ctx context.Context) (*resource.Resource, error) {
+	opts := []resource.Option{
+		resource.WithSchemaURL(semconv.SchemaURL),
+		resource.WithTelemetrySDK(),
+	}
+	for _, d := range C.Otel.Resource.Detectors {
+		switch d {
+		case ResourceDetectorEnv:
+			opts = append(opts, resource.WithFromEnv())
+		case ResourceDetectorHost:
+			opts = append(opts, resource.WithHost())
+		case ResourceDetectorOS:
+			opts = append(opts, resource.WithOS())
+		case ResourceDetectorProcess:
+			// not resource.WithProcess, the command args may hold secrets
+			opts = append(opts,
+				resource.WithProcessPID(),
+				resource.WithProcessExecutableName(),
+				resource.WithProcessExecutablePath(),
+				resource.WithProcessOwner(),
+				resource.WithProcessRuntimeName(),
+				resource.WithProcessRuntimeVersion(),
+				resource.WithProcessRuntimeDescription(),
+			)
+		case ResourceDetectorContainer:
+			opts = append(opts, resource.WithContainer())
+		}
+	}
+
+	env := C.Otel.Resource.Environment
+	if env == "" {
+		env = C.Profile
+	}
+	attrs := []attribute.KeyValue{
+		semconv.ServiceName(C.Service.Name),
+		semconv.ServiceVersion(buildinfo.Get().Version),
+	}
+	if env != "" {
+		attrs = append(attrs, semconv.DeploymentEnvironment(env))
+	}
+	for k, v := range C.Otel.Resource.Attributes {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	opts = append(opts, resource.WithAttributes(attrs...))
+
+	res, err := resource.New(ctx, opts...)
+	if err != nil {
+		return res, errors.Wrap(err, "detect otel resource failed")
+	}
+	return res, nil
+}
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/goccy/go-json"
+	"github.com/tlipoca9/errors"
+)
+
+// SecretProvider resolves the references of secret config values. A reference
+// is "<scheme>:<ref>", e.g. "file:///run/secrets/db_password" or "env:DB_PASSWORD".
+// Values without a registered scheme are used as is.
+type SecretProvider interface {
+	Scheme() string
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+var (
+	secretProvidersMux sync.RWMutex
+	secretProviders    = map[string]SecretProvider{
+		fileSecretProvider{}.Scheme(): fileSecretProvider{},
+		envSecretProvider{}.Scheme():  envSecretProvider{},
+	}
+)
+
+// RegisterSecretProvider makes p resolve the references of its scheme,
+// replacing any provider registered for the same scheme.
+func RegisterSecretProvider(p SecretProvider) {
+	secretProvidersMux.Lock()
+	defer secretProvidersMux.Unlock()
+	secretProviders[p.Scheme()] = p
+}
+
+func secretProvider(scheme string) (SecretProvider, bool) {
+	secretProvidersMux.RLock()
+	defer secretProvidersMux.RUnlock()
+	p, ok := secretProviders[scheme]
+	return p, ok
+}
+
+// resolveSecrets replaces the references in the fields tagged `secret:"true"`
+// with the values they point to.
+func resolveSecrets(ctx context.Context, cfg *Config) ValidationError {
+	var (
+		errs ValidationError
+		encp SecretProvider
+	)
+	if cfg.Secrets.File != "" {
+		encp = &encryptedFileSecretProvider{path: cfg.Secrets.File, keyEnv: cfg.Secrets.KeyEnv}
+	}
+	resolve := func(key, v string) string {
+		scheme, ref, ok := strings.Cut(v, ":")
+		if !ok {
+			return v
+		}
+		p, ok := secretProvider(scheme)
+		if scheme == encryptedFileScheme {
+			if encp == nil {
+				errs = append(errs, FieldError{Key: key, Message: "encfile reference needs secrets.file"})
+				return ""
+			}
+			p, ok = encp, true
+		}
+		if !ok {
+			return v
+		}
+		ret, err := p.Resolve(ctx, ref)
+		if err != nil {
+			errs = append(errs, FieldError{Key: key, Message: "resolve secret failed: " + err.Error()})
+		}
+		return ret
+	}
+
+	v := reflect.ValueOf(cfg).Elem()
+	for _, f := range configFields() {
+		if f.Tag.Get("secret") != "true" {
+			continue
+		}
+		fv := v.FieldByIndex(f.Index)
+		switch {
+		case f.Type.Kind() == reflect.String:
+			fv.SetString(resolve(f.Key, fv.String()))
+		case f.isStringMap():
+			iter := fv.MapRange()
+			for iter.Next() {
+				key := f.Key + "." + iter.Key().String()
+				fv.SetMapIndex(iter.Key(), reflect.ValueOf(resolve(key, iter.Value().String())))
+			}
+		}
+	}
+	return errs
+}
+
+// fileSecretProvider reads "file:///path" references, trailing newlines are
+// trimmed as most secret files end with one.
+type fileSecretProvider struct{}
+
+func (fileSecretProvider) Scheme() string { return "file" }
+
+func (fileSecretProvider) Resolve(_ context.Context, ref string) (string, error) {
+	b, err := os.ReadFile(strings.TrimPrefix(ref, "//"))
+	if err != nil {
+		return "", errors.Wrap(err, "read secret file failed")
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// envSecretProvider reads "env:NAME" references.
+type envSecretProvider struct{}
+
+func (envSecretProvider) Scheme() string { return "env" }
+
+func (envSecretProvider) Resolve(_ context.Context, ref string) (string, error) {
+	v, ok := os.LookupEnv(ref)
+	if !ok {
+		return "", errors.Newf("environment variable %s is not set", ref)
+	}
+	return v, nil
+}
+
+const encryptedFileScheme = "encfile"
+
+// encryptedFileSecretProvider reads "encfile:name" references from the
+// secrets.file config, a json object of names to values sealed with
+// AES-256-GCM under the base64 key stored in the secrets.key_env variable.
+type encryptedFileSecretProvider struct {
+	path   string
+	keyEnv string
+
+	once    sync.Once
+	secrets map[string]string
+	err     error
+}
+
+func (p *encryptedFileSecretProvider) Scheme() string { return encryptedFileScheme }
+
+func (p *encryptedFileSecretProvider) Resolve(_ context.Context, ref string) (string, error) {
+	p.once.Do(func() {
+		var data []byte
+		data, p.err = os.ReadFile(p.path)
+		if p.err != nil {
+			p.err = errors.Wrap(p.err, "read secrets file failed")
+			return
+		}
+		p.secrets, p.err = DecryptSecrets(os.Getenv(p.keyEnv), data)
+	})
+	if p.err != nil {
+		return "", p.err
+	}
+	v, ok := p.secrets[ref]
+	if !ok {
+		return "", errors.Newf("secret %s not found in %s", ref, p.path)
+	}
+	return v, nil
+}
+
+// NewSecretsKey returns a random base64 key for EncryptSecrets.
+func NewSecretsKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", errors.Wrap(err, "generate secrets key failed")
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// EncryptSecrets seals secrets into the content of an encrypted secrets file.
+func EncryptSecrets(key string, secrets map[string]string) ([]byte, error) {
+	aead, err := secretsAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal secrets failed")
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.Wrap(err, "generate nonce failed")
+	}
+	sealed := aead.Seal(nonce, nonce, plain, nil)
+	return []byte(base64.StdEncoding.EncodeToString(sealed) + "\n"), nil
+}
+
+// DecryptSecrets opens the content of an encrypted secrets file.
+func DecryptSecrets(key string, data []byte) (map[string]string, error) {
+	aead, err := secretsAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, errors.Wrap(err, "decode secrets file failed")
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("secrets file is truncated")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "decrypt secrets file failed")
+	}
+	var secrets map[string]string
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, errors.Wrap(err, "unmarshal secrets failed")
+	}
+	return secrets, nil
+}
+
+func secretsAEAD(key string) (cipher.AEAD, error) {
+	if key == "" {
+		return nil, errors.New("secrets key is empty")
+	}
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "decode secrets key failed")
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "create secrets cipher failed")
+	}
+	aead, err := cipher.NewGCM(block)
+	return aead, errors.Wrap(err, "create secrets aead failed")
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncryptSecrets(t *testing.T) {
+	key, err := NewSecretsKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := EncryptSecrets(key, map[string]string{"db": "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secrets, err := DecryptSecrets(key, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secrets["db"] != "s3cret" {
+		t.Errorf("DecryptSecrets() = %v, want db=s3cret", secrets)
+	}
+
+	otherKey, _ := NewSecretsKey()
+	tests := []struct {
+		name string
+		key  string
+		data []byte
+	}{
+		{"wrong key", otherKey, data},
+		{"empty key", "", data},
+		{"invalid key", "not base64!", data},
+		{"short key", "c2hvcnQ=", data},
+		{"invalid data", key, []byte("not base64!")},
+		{"truncated data", key, []byte("AAAA")},
+		{"tampered data", key, append([]byte("AAAA"), data[4:]...)},
+	}
+	for _, tt := range tests {
+		if _, err := DecryptSecrets(tt.key, tt.data); err == nil {
+			t.Errorf("DecryptSecrets() with %s succeeded", tt.name)
+		}
+	}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "password")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	key, _ := NewSecretsKey()
+	data, _ := EncryptSecrets(key, map[string]string{"db": "from-encfile"})
+	encFile := filepath.Join(dir, "secrets.enc")
+	if err := os.WriteFile(encFile, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_SECRET", "from-env")
+	t.Setenv("TEST_SECRETS_KEY", key)
+
+	tests := []struct {
+		name    string
+		value   string
+		file    string
+		want    string
+		wantErr bool
+	}{
+		{"plain", "plain", "", "plain", false},
+		{"unknown scheme", "user:pass", "", "user:pass", false},
+		{"file", "file://" + secretFile, "", "from-file", false},
+		{"missing file", "file://" + filepath.Join(dir, "missing"), "", "", true},
+		{"env", "env:TEST_SECRET", "", "from-env", false},
+		{"missing env", "env:TEST_SECRET_MISSING", "", "", true},
+		{"encfile", "encfile:db", encFile, "from-encfile", false},
+		{"missing encfile secret", "encfile:other", encFile, "", true},
+		{"encfile without secrets.file", "encfile:db", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg Config
+			cfg.Database.Password = tt.value
+			cfg.Secrets.File = tt.file
+			cfg.Secrets.KeyEnv = "TEST_SECRETS_KEY"
+
+			errs := resolveSecrets(context.Background(), &cfg)
+			if (len(errs) > 0) != tt.wantErr {
+				t.Fatalf("resolveSecrets() errors = %v, want error %t", errs, tt.wantErr)
+			}
+			if !tt.wantErr && cfg.Database.Password != tt.want {
+				t.Errorf("database.password = %q, want %q", cfg.Database.Password, tt.want)
+			}
+			if tt.wantErr && errs[0].Key != "database.password" {
+				t.Errorf("error key = %q, want database.password", errs[0].Key)
+			}
+		})
+	}
+}
@@ -50,20 +50,26 @@ func WaitForExit(ctx context.Context) {
 
 	select {
 	case <-ctx.Done():
+		// commands which did not bootstrap anything exit quietly
+		if defaultShutdownManager.Len() == 0 {
+			return
+		}
 		log.Info("context done, exit")
 	case s := <-ch:
 		log.Info("catch exit signal", slog.String("signal", s.String()))
 	}
 
-	maxTimeout := C.Service.ShutdownTimeout * time.Duration(len(defaultShutdownManager.shutdowns))
+	timeout := Current().Service.ShutdownTimeout
+	maxTimeout := timeout * time.Duration(len(defaultShutdownManager.shutdowns))
 	ctx, cancel := context.WithTimeout(context.Background(), maxTimeout)
 	defer cancel()
 	log.Info(
 		"start shutdown",
-		slog.Duration("timeout", C.Service.ShutdownTimeout),
+		slog.Duration("timeout", timeout),
 		slog.Duration("max_timeout", maxTimeout),
 	)
-	defaultShutdownManager.Shutdown(ctx, C.Service.ShutdownTimeout)
+	defaultShutdownManager.Shutdown(ctx, timeout)
+	closeLogSinks()
 }
 
 type shutdownManager struct {
@@ -77,6 +83,12 @@ func (s *shutdownManager) DeferShutdown(sd NamedShutdown) {
 	s.shutdowns = append(s.shutdowns, sd)
 }
 
+func (s *shutdownManager) Len() int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return len(s.shutdowns)
+}
+
 func (s *shutdownManager) Shutdown(ctx context.Context, timeout time.Duration) {
 	s.mux.Lock()
 	defer s.mux.Unlock()
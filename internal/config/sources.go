@@ -0,0 +1,227 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	kjson "github.com/knadh/koanf/parsers/json"
+	"github.com/knadh/koanf/parsers/toml"
+	"github.com/knadh/koanf/parsers/yaml"
+	"github.com/knadh/koanf/providers/confmap"
+	"github.com/knadh/koanf/providers/env"
+	"github.com/knadh/koanf/providers/file"
+	"github.com/knadh/koanf/v2"
+	"github.com/tlipoca9/errors"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	envPrefix = "ASTA_"
+
+	FlagConfig        = "config"
+	FlagProfile       = "profile"
+	DefaultConfigFile = "etc/config.toml"
+
+	profilesKey = "profiles"
+)
+
+// OptionsFromCLI returns the Options given by the global flags of c: the
+// files of --config or $ASTA_CONFIG, the profile of --profile or $ASTA_PROFILE
+// and the config fields set as flags.
+func OptionsFromCLI(c *cli.Context) Options {
+	var opts Options
+	if c.IsSet(FlagConfig) {
+		opts.Files = c.StringSlice(FlagConfig)
+	}
+	opts.Profile = c.String(FlagProfile)
+	opts.Overrides = flagValues(c)
+	return opts
+}
+
+// configFiles returns the files of opts, or DefaultConfigFile if it exists.
+func configFiles(opts Options) []string {
+	if len(opts.Files) > 0 {
+		return opts.Files
+	}
+	if _, err := os.Stat(DefaultConfigFile); err == nil {
+		return []string{DefaultConfigFile}
+	}
+	return nil
+}
+
+// layer is a source of config values, layers are merged in order.
+type layer struct {
+	name     string
+	provider koanf.Provider
+	parser   koanf.Parser
+}
+
+// configLayers returns the config sources of opts with the precedence
+// defaults < files < env < overrides.
+func configLayers(opts Options) ([]layer, error) {
+	ret := []layer{{name: "default", provider: defaultProvider()}}
+	for _, path := range configFiles(opts) {
+		parser, err := parserFor(path)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, layer{name: path, provider: file.Provider(path), parser: parser})
+	}
+	if !opts.SkipEnv {
+		ret = append(ret, layer{name: "env", provider: envProvider()})
+	}
+	return append(ret, layer{name: "flag", provider: confmap.Provider(opts.Overrides, ".")}), nil
+}
+
+// fieldKey returns the config field a koanf key belongs to, e.g. the values
+// of maps such as "otel.headers.authorization" belong to "otel.headers".
+func fieldKey(key string) string {
+	for _, f := range configFields() {
+		if key == f.Key || strings.HasPrefix(key, f.Key+".") {
+			return f.Key
+		}
+	}
+	return key
+}
+
+func parserFor(path string) (koanf.Parser, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		return toml.Parser(), nil
+	case ".yaml", ".yml":
+		return yaml.Parser(), nil
+	case ".json":
+		return kjson.Parser(), nil
+	default:
+		return nil, errors.Newf("unsupported config file %s, expect .toml, .yaml, .yml or .json", path)
+	}
+}
+
+// defaultProvider returns the values of the `default` tags.
+func defaultProvider() *confmap.Confmap {
+	mp := make(map[string]any)
+	for _, f := range configFields() {
+		v, ok := f.Tag.Lookup("default")
+		if !ok {
+			continue
+		}
+		switch {
+		case f.isStringMap():
+			mp[f.Key] = parseStringMap([]string{v})
+		case f.isStringSlice():
+			mp[f.Key] = strings.Split(v, ",")
+		default:
+			mp[f.Key] = v
+		}
+	}
+	return confmap.Provider(mp, ".")
+}
+
+// EnvName returns the environment variable that overrides the given config key,
+// e.g. "database.password" => "ASTA_DATABASE_PASSWORD".
+func EnvName(key string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// envProvider maps ASTA_* environment variables onto config keys. Unknown
+// variables are ignored, so that underscores inside key names stay unambiguous.
+func envProvider() *env.Env {
+	known := make(map[string]field)
+	for _, f := range configFields() {
+		known[EnvName(f.Key)] = f
+	}
+
+	return env.ProviderWithValue(envPrefix, ".", func(name, value string) (string, any) {
+		f, ok := known[name]
+		if !ok {
+			return "", nil
+		}
+		switch {
+		case f.isStringMap():
+			return f.Key, parseStringMap([]string{value})
+		case f.isStringSlice():
+			return f.Key, strings.Split(value, ",")
+		default:
+			return f.Key, value
+		}
+	})
+}
+
+// Flags returns a cli flag for every config field, named after its key,
+// e.g. --service.addr. Lists of structs such as log.sinks have none, they are
+// only set by the files or as json by the environment.
+func Flags() []cli.Flag {
+	ret := []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:    FlagConfig,
+			Aliases: []string{"c"},
+			Usage:   "config file (.toml, .yaml, .json), repeat to layer files, later ones win",
+			EnvVars: []string{envPrefix + "CONFIG"},
+		},
+		&cli.StringFlag{
+			Name:    FlagProfile,
+			Aliases: []string{"p"},
+			Usage:   "profile whose [profiles.<name>] sections overlay the config files",
+			EnvVars: []string{envPrefix + "PROFILE"},
+		},
+	}
+	for _, f := range configFields() {
+		usage := usages[f.Key]
+		if usage != "" {
+			usage += " "
+		}
+		usage += "[$" + EnvName(f.Key) + "]"
+		def := f.Tag.Get("default")
+
+		var flag cli.Flag
+		switch {
+		case f.isDuration():
+			flag = &cli.DurationFlag{Name: f.Key, Usage: usage, DefaultText: def, Category: "config"}
+		case f.isStringMap():
+			flag = &cli.StringSliceFlag{Name: f.Key, Usage: usage + " (key=value)", DefaultText: def, Category: "config"}
+		case f.isStringSlice():
+			flag = &cli.StringSliceFlag{Name: f.Key, Usage: usage, DefaultText: def, Category: "config"}
+		case f.Type.Kind() == reflect.String:
+			flag = &cli.StringFlag{Name: f.Key, Usage: usage, DefaultText: def, Category: "config"}
+		case f.Type.Kind() == reflect.Bool:
+			flag = &cli.BoolFlag{Name: f.Key, Usage: usage, DefaultText: def, Category: "config"}
+		case f.Type.Kind() == reflect.Int:
+			flag = &cli.IntFlag{Name: f.Key, Usage: usage, DefaultText: def, Category: "config"}
+		case f.Type.Kind() == reflect.Float64:
+			flag = &cli.Float64Flag{Name: f.Key, Usage: usage, DefaultText: def, Category: "config"}
+		default:
+			continue
+		}
+		ret = append(ret, flag)
+	}
+	return ret
+}
+
+// flagValues returns the config values explicitly set on the command line.
+func flagValues(c *cli.Context) map[string]any {
+	mp := make(map[string]any)
+	for _, f := range configFields() {
+		if !c.IsSet(f.Key) {
+			continue
+		}
+		switch {
+		case f.isDuration():
+			mp[f.Key] = c.Duration(f.Key)
+		case f.isStringMap():
+			mp[f.Key] = parseStringMap(c.StringSlice(f.Key))
+		case f.isStringSlice():
+			mp[f.Key] = c.StringSlice(f.Key)
+		case f.Type.Kind() == reflect.String:
+			mp[f.Key] = c.String(f.Key)
+		case f.Type.Kind() == reflect.Bool:
+			mp[f.Key] = c.Bool(f.Key)
+		case f.Type.Kind() == reflect.Int:
+			mp[f.Key] = c.Int(f.Key)
+		case f.Type.Kind() == reflect.Float64:
+			mp[f.Key] = c.Float64(f.Key)
+		}
+	}
+	return mp
+}
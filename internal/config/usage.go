@@ -0,0 +1,77 @@
+package config
+
+// usages describe the config keys in the flags and the sample config. They are
+// kept apart from the struct tags, which would otherwise outgrow the line length.
+var usages = map[string]string{
+	"service.name":             "service name",
+	"service.addr":             "http listen address",
+	"service.shutdown_timeout": "timeout of each shutdown step",
+	"service.console":          "human readable logs instead of json",
+	"service.debug":            "debug logs and debug endpoints",
+	"service.log_levels":       "levels of the named loggers and their children, e.g. database = \"debug\"",
+	"service.admin_token":      "bearer token of the admin endpoints such as /debug/loglevel, else only served in debug",
+
+	"service.debug_log.header":        "request header enabling the debug logs of the request",
+	"service.debug_log.allowed_cidrs": "networks whose requests enable debug logs with the header set to 1",
+	"service.debug_log.key":           "hmac key of the signed header values, see asta config sign-debug-log",
+	"service.debug_log.max_ttl":       "max validity of the signed header values",
+
+	"log.sinks": "outputs of the logs, stderr if empty, json in env",
+
+	"otel.collector_endpoint": "otlp collector endpoint, spans go to otel.file.path if empty",
+	"otel.protocol":           "otlp protocol, grpc or http/protobuf",
+	"otel.insecure":           "disable transport security of the otlp exporter",
+	"otel.headers":            "headers sent with every otlp request",
+	"otel.compression":        "otlp compression, gzip or none",
+	"otel.timeout":            "timeout of each otlp export",
+	"otel.propagators":        "context propagators, extracted in order so later ones win, all are injected",
+
+	"otel.tls.ca_file":              "ca certificate of the collector",
+	"otel.tls.cert_file":            "client certificate for mtls",
+	"otel.tls.key_file":             "client key for mtls",
+	"otel.tls.server_name":          "override the server name used to verify the collector",
+	"otel.tls.insecure_skip_verify": "skip verifying the collector certificate",
+
+	"otel.batch.max_queue_size":        "max spans buffered before dropping",
+	"otel.batch.max_export_batch_size": "max spans per export",
+	"otel.batch.batch_timeout":         "max delay before exporting a batch",
+	"otel.batch.export_timeout":        "max duration of a batch export",
+
+	"otel.file.path":            "file the spans are written to without a collector",
+	"otel.file.max_size_mb":     "rotate the file before it grows over this many megabytes, 0 disables it",
+	"otel.file.rotate_interval": "rotate the file once it is older than this, 0 disables it",
+	"otel.file.max_files":       "rotated files kept, 0 keeps all of them",
+	"otel.file.compress":        "gzip the rotated files",
+
+	"otel.metrics.otlp":     "also push the otel metrics to the collector, they are always on /metrics",
+	"otel.metrics.interval": "interval of the otlp metric exports",
+	"otel.metrics.runtime":  "collect the go runtime metrics of otel",
+
+	"otel.logs.enabled": "also export the logs as otel log records, to the collector or otel.logs.path",
+	"otel.logs.path":    "file the log records are written to without a collector, rotated like otel.file",
+
+	"otel.sampler.type":                 "trace sampler, always, never, ratio, parent_ratio, rules or rate_limited",
+	"otel.sampler.ratio":                "fraction of the traces sampled by ratio, parent_ratio and matching no rule",
+	"otel.sampler.rate_limit":           "max traces sampled per second by rate_limited",
+	"otel.sampler.always_sample_errors": "export the spans ending with an error even if their trace is unsampled",
+	"otel.sampler.rules":                "rules of the rules sampler, the first matching one decides, json in env",
+
+	"otel.resource.environment": "deployment.environment attribute, the active profile if empty",
+	"otel.resource.detectors":   "resource detectors, env reads OTEL_RESOURCE_ATTRIBUTES",
+	"otel.resource.attributes":  "custom resource attributes, override the detected ones",
+
+	"database.db_name":            "mysql database name",
+	"database.username":           "mysql username",
+	"database.password":           "mysql password, or a file:, env: or encfile: reference",
+	"database.host":               "mysql host",
+	"database.port":               "mysql port",
+	"database.max_open_conns":     "max open connections, 0 is unlimited",
+	"database.max_idle_conns":     "max idle connections, 0 keeps none",
+	"database.conn_max_lifetime":  "max lifetime of a connection, 0 is unlimited",
+	"database.conn_max_idle_time": "max idle time of a connection, 0 is unlimited",
+
+	"cache.address": "redis address",
+
+	"secrets.file":    "encrypted secrets file for encfile:<name> references",
+	"secrets.key_env": "environment variable holding the base64 key of the secrets file",
+}
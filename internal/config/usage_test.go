@@ -0,0 +1,21 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUsages(t *testing.T) {
+	keys := make(map[string]bool)
+	for _, f := range fields(reflect.TypeOf(Config{})) {
+		keys[f.Key] = true
+		if usages[f.Key] == "" {
+			t.Errorf("%s has no usage", f.Key)
+		}
+	}
+	for key := range usages {
+		if !keys[key] {
+			t.Errorf("usage of unknown key %s", key)
+		}
+	}
+}
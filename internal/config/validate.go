@@ -0,0 +1,198 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tlipoca9/errors"
+)
+
+// ValidationError aggregates every invalid field of a config, so that all of
+// them can be fixed at once.
+type ValidationError []FieldError
+
+type FieldError struct {
+	Key     string
+	Value   any
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString("invalid config:")
+	for _, fe := range e {
+		fmt.Fprintf(&b, "\n  - %s: %s", fe.Key, fe.Message)
+		if fe.Value != nil {
+			fmt.Fprintf(&b, " (got %q)", fmt.Sprint(fe.Value))
+		}
+	}
+	return b.String()
+}
+
+// Validate checks every field against the rules of its `validate` tag.
+// Rules are separated by commas:
+//
+//	required    the value must not be empty
+//	hostport    the value must be a "host:port" address
+//	file        the value, if set, must be an existing file
+//	oneof=a b   the value, or every value of a list, must be one of the space separated values
+//	min=n       numbers and durations must be >= n
+//	max=n       numbers and durations must be <= n
+//	gt=n        numbers and durations must be > n
+//	cidr        the value, or every value of a list, must be a network such as 10.0.0.0/8 or an ip
+//	loglevel    the value, or every value of a map, must be a slog level such as debug or warn+2
+func (c *Config) Validate() error {
+	if errs := c.validate(); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (c *Config) validate() ValidationError {
+	return validateFields(reflect.ValueOf(c).Elem(), configFields(), "")
+}
+
+// validateFields checks the fields of v, prefixing their keys with prefix.
+// The elements of struct slices, e.g. otel.sampler.rules, are checked
+// against the rules of their own fields.
+func validateFields(v reflect.Value, fs []field, prefix string) ValidationError {
+	var errs ValidationError
+	for _, f := range fs {
+		fv := v.FieldByIndex(f.Index)
+		key := prefix + f.Key
+		if f.isStructSlice() {
+			elemFields := fields(f.Type.Elem())
+			for i := range fv.Len() {
+				errs = append(errs, validateFields(fv.Index(i), elemFields, fmt.Sprintf("%s[%d].", key, i))...)
+			}
+		}
+		rules := f.Tag.Get("validate")
+		if rules == "" {
+			continue
+		}
+		for _, rule := range strings.Split(rules, ",") {
+			name, arg, _ := strings.Cut(rule, "=")
+			if msg := checkRule(f, fv, name, arg); msg != "" {
+				var value any
+				if f.Tag.Get("secret") != "true" && !fv.IsZero() {
+					value = fv.Interface()
+				}
+				errs = append(errs, FieldError{Key: key, Value: value, Message: msg})
+				break
+			}
+		}
+	}
+	return errs
+}
+
+// checkRule returns a description of the violated rule, or "" if v is valid.
+// Rules other than required pass on empty values.
+func checkRule(f field, v reflect.Value, name, arg string) string {
+	if name == "required" {
+		if v.IsZero() {
+			return "is required"
+		}
+		return ""
+	}
+	if v.IsZero() && name != "min" && name != "gt" {
+		return ""
+	}
+
+	switch name {
+	case "hostport":
+		if _, _, err := net.SplitHostPort(v.String()); err != nil {
+			return "must be a host:port address"
+		}
+	case "file":
+		if info, err := os.Stat(v.String()); err != nil || info.IsDir() {
+			return "must be an existing file"
+		}
+	case "oneof":
+		values := []string{v.String()}
+		if f.isStringSlice() {
+			values = v.Interface().([]string)
+		}
+		for _, value := range values {
+			if !slices.Contains(strings.Fields(arg), value) {
+				return "must be one of " + strings.Join(strings.Fields(arg), ", ")
+			}
+		}
+	case "cidr":
+		values := []string{v.String()}
+		if f.isStringSlice() {
+			values = v.Interface().([]string)
+		}
+		for _, value := range values {
+			if _, err := parsePrefix(value); err != nil {
+				return "must be a network such as 10.0.0.0/8 or an ip"
+			}
+		}
+	case "loglevel":
+		values := []string{}
+		if v.Kind() == reflect.Map {
+			for _, value := range v.Interface().(map[string]string) {
+				values = append(values, value)
+			}
+		} else {
+			values = append(values, v.String())
+		}
+		for _, value := range values {
+			var level slog.Level
+			if err := level.UnmarshalText([]byte(value)); err != nil {
+				return "must be a log level such as debug, info, warn or error"
+			}
+		}
+	case "min", "max", "gt":
+		n, limit, err := numbers(f, v, arg)
+		if err != nil {
+			return fmt.Sprintf("invalid rule %s=%s", name, arg)
+		}
+		switch {
+		case name == "min" && n < limit:
+			return "must be >= " + arg
+		case name == "max" && n > limit:
+			return "must be <= " + arg
+		case name == "gt" && n <= limit:
+			return "must be > " + arg
+		}
+	default:
+		return "unknown rule " + name
+	}
+	return ""
+}
+
+func numbers(f field, v reflect.Value, arg string) (float64, float64, error) {
+	if f.isDuration() {
+		limit, err := time.ParseDuration(arg)
+		return float64(v.Int()), float64(limit), err
+	}
+	limit, err := strconv.ParseFloat(arg, 64)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), limit, err
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), limit, err
+	default:
+		return 0, 0, errors.Newf("%s is not a number", f.Key)
+	}
+}
+
+// parsePrefix parses a network, an ip is the network of this ip only.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if !strings.Contains(s, "/") {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	return netip.ParsePrefix(s)
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, "ca.pem", "")
+	base, _, err := Load(Options{Files: []string{writeFile(t, dir, "config.toml", required)}, SkipEnv: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(c *Config)
+		// want is the only FieldError, nil if the config is valid
+		want *FieldError
+	}{
+		{"valid", func(*Config) {}, nil},
+		{"required", func(c *Config) { c.Service.Name = "" }, &FieldError{"service.name", nil, "is required"}},
+		{
+			"hostport", func(c *Config) { c.Service.Addr = "8080" },
+			&FieldError{"service.addr", "8080", "must be a host:port address"},
+		},
+		{"file", func(c *Config) { c.Otel.TLS.CAFile = file }, nil},
+		{
+			"missing file", func(c *Config) { c.Otel.TLS.CAFile = filepath.Join(dir, "missing") },
+			&FieldError{"otel.tls.ca_file", filepath.Join(dir, "missing"), "must be an existing file"},
+		},
+		{
+			"directory", func(c *Config) { c.Otel.TLS.CertFile = dir },
+			&FieldError{"otel.tls.cert_file", dir, "must be an existing file"},
+		},
+		{
+			"oneof", func(c *Config) { c.Otel.Protocol = "udp" },
+			&FieldError{"otel.protocol", "udp", "must be one of grpc, http/protobuf"},
+		},
+		{"oneof list", func(c *Config) { c.Otel.Propagators = []string{"b3", "xray"} }, nil},
+		{
+			"oneof list element", func(c *Config) { c.Otel.Propagators = []string{"b3", "zipkin"} },
+			&FieldError{
+				"otel.propagators", []string{"b3", "zipkin"},
+				"must be one of tracecontext, baggage, b3, b3multi, jaeger, xray, ot",
+			},
+		},
+		{"min", func(c *Config) { c.Database.MaxOpenConns = -1 }, &FieldError{"database.max_open_conns", -1, "must be >= 0"}},
+		{"min of zero", func(c *Config) { c.Database.Port = 0 }, &FieldError{"database.port", nil, "must be >= 1"}},
+		{"max", func(c *Config) { c.Database.Port = 65536 }, &FieldError{"database.port", 65536, "must be <= 65535"}},
+		{"max float", func(c *Config) { c.Otel.Sampler.Ratio = 1.5 }, &FieldError{"otel.sampler.ratio", 1.5, "must be <= 1"}},
+		{"gt", func(c *Config) { c.Otel.Sampler.RateLimit = 0 }, &FieldError{"otel.sampler.rate_limit", nil, "must be > 0"}},
+		{
+			"gt duration", func(c *Config) { c.Service.ShutdownTimeout = -time.Second },
+			&FieldError{"service.shutdown_timeout", -time.Second, "must be > 0"},
+		},
+		{"cidr", func(c *Config) { c.Service.DebugLog.AllowedCIDRs = []string{"10.0.0.0/8", "::1"} }, nil},
+		{
+			"cidr element", func(c *Config) { c.Service.DebugLog.AllowedCIDRs = []string{"10.0.0.0/8", "10.0.0.0/33"} },
+			&FieldError{
+				"service.debug_log.allowed_cidrs", []string{"10.0.0.0/8", "10.0.0.0/33"},
+				"must be a network such as 10.0.0.0/8 or an ip",
+			},
+		},
+		{"loglevel", func(c *Config) { c.Service.LogLevels = map[string]string{"db": "warn+2"} }, nil},
+		{
+			"loglevel value", func(c *Config) { c.Service.LogLevels = map[string]string{"db": "verbose"} },
+			&FieldError{
+				"service.log_levels", map[string]string{"db": "verbose"},
+				"must be a log level such as debug, info, warn or error",
+			},
+		},
+		{
+			"sink", func(c *Config) { c.Log.Sinks = []LogSink{{Type: "stderr"}, {Type: "kafka"}} },
+			&FieldError{"log.sinks[1].type", "kafka", "must be one of stderr, file, syslog"},
+		},
+		{
+			"sink level", func(c *Config) { c.Log.Sinks = []LogSink{{Type: "stderr", Level: "loud"}} },
+			&FieldError{"log.sinks[0].level", "loud", "must be a log level such as debug, info, warn or error"},
+		},
+		{
+			"sampler rule", func(c *Config) { c.Otel.Sampler.Rules = []SamplerRule{{Sample: "always"}} },
+			&FieldError{"otel.sampler.rules[0].route", nil, "is required"},
+		},
+		{
+			"sampler rule sample", func(c *Config) {
+				c.Otel.Sampler.Rules = []SamplerRule{{Route: "/a", Sample: "always"}, {Route: "/b", Sample: "sometimes"}}
+			},
+			&FieldError{"otel.sampler.rules[1].sample", "sometimes", "must be one of always, never, ratio"},
+		},
+	}
+	for _, tt := range tests {
+		cfg := *base
+		tt.mutate(&cfg)
+		errs := cfg.validate()
+		switch {
+		case tt.want == nil && len(errs) > 0:
+			t.Errorf("%s: validate() = %v, want no error", tt.name, errs)
+		case tt.want != nil && (len(errs) != 1 || !reflect.DeepEqual(errs[0], *tt.want)):
+			t.Errorf("%s: validate() = %#v, want %#v", tt.name, errs, *tt.want)
+		}
+	}
+}
+
+func TestLoadValidationError(t *testing.T) {
+	t.Setenv("ASTA_OTEL_PROTOCOL", "udp")
+	t.Setenv("ASTA_LOG_SINKS", `[{"type": "kafka"}]`)
+	t.Setenv("ASTA_DATABASE_DB_NAME", "")
+	t.Setenv("ASTA_DATABASE_USERNAME", "")
+
+	cfg, _, err := Load(Options{})
+	errs, ok := err.(ValidationError)
+	if !ok {
+		t.Fatalf("Load() error = %v, want a ValidationError", err)
+	}
+	if cfg == nil || cfg.Otel.Protocol != "udp" {
+		t.Errorf("Load() config = %v, want the invalid config", cfg)
+	}
+	var keys []string
+	for _, fe := range errs {
+		keys = append(keys, fe.Key)
+	}
+	want := []string{"log.sinks[0].type", "otel.protocol", "database.db_name", "database.username"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("invalid keys = %v, want %v", keys, want)
+	}
+
+	msg := err.Error()
+	for _, want := range []string{
+		"invalid config:",
+		"\n  - log.sinks[0].type: must be one of stderr, file, syslog (got \"kafka\")",
+		"\n  - otel.protocol: must be one of grpc, http/protobuf (got \"udp\")",
+		"\n  - database.db_name: is required\n",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Error() = %q, want it to contain %q", msg, want)
+		}
+	}
+}
@@ -7,11 +7,15 @@ import (
 	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/tlipoca9/leaf/gormleaf"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 
 	"github.com/tlipoca9/asta/internal/config"
+	"github.com/tlipoca9/asta/pkg/gormx"
 )
 
 var (
@@ -34,17 +38,27 @@ type Config struct {
 	Password string
 	Host     string
 	Port     int
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
 }
 
 func New() Service {
 	if _s == nil {
 		_init.Do(func() {
-			_s = newService(slog.Default(), Config{
+			_s = newService(config.Logger("database"), Config{
 				DBName:   config.C.Database.DBName,
 				Username: config.C.Database.Username,
 				Password: config.C.Database.Password,
 				Host:     config.C.Database.Host,
 				Port:     config.C.Database.Port,
+
+				MaxOpenConns:    config.C.Database.MaxOpenConns,
+				MaxIdleConns:    config.C.Database.MaxIdleConns,
+				ConnMaxLifetime: config.C.Database.ConnMaxLifetime,
+				ConnMaxIdleTime: config.C.Database.ConnMaxIdleTime,
 			})
 		})
 	}
@@ -66,10 +80,26 @@ func newService(log *slog.Logger, conf Config) Service {
 	if err != nil {
 		panic(err)
 	}
+	err = db.Use(gormx.NewTracingPlugin(gormx.WithAttributes(
+		semconv.DBNamespace(conf.DBName),
+		semconv.ServerAddress(conf.Host),
+		semconv.ServerPort(conf.Port),
+	)))
+	if err != nil {
+		panic(err)
+	}
 	sqlDB, err := db.DB()
 	if err != nil {
 		panic(err)
 	}
+	sqlDB.SetMaxOpenConns(conf.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(conf.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(conf.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(conf.ConnMaxIdleTime)
+	// see https://pkg.go.dev/github.com/prometheus/client_golang/prometheus/collectors#NewDBStatsCollector
+	// prefixed like the other metrics of the service, e.g. asta_go_sql_open_connections
+	prometheus.WrapRegistererWithPrefix(config.MetricsNamespace+"_", prometheus.DefaultRegisterer).
+		MustRegister(collectors.NewDBStatsCollector(sqlDB, conf.DBName))
 	config.DeferShutdown("database", sqlDB.Close)
 
 	s := &service{
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/tlipoca9/asta/internal/config"
+)
+
+// AdminOnly serves the admin endpoints with service.debug, or to the requests
+// carrying service.admin_token as bearer token. They are not found otherwise.
+func (s *Server) AdminOnly() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		cfg := config.Current()
+		if cfg.Service.Debug {
+			return c.Next()
+		}
+		if cfg.Service.AdminToken == "" {
+			return fiber.ErrNotFound
+		}
+		token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(cfg.Service.AdminToken)) != 1 {
+			return fiber.ErrUnauthorized
+		}
+		return c.Next()
+	}
+}
+
+func (s *Server) GetLogLevelHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.JSON(config.GetLogLevels())
+	}
+}
+
+// SetLogLevelHandler changes the levels given in the body, e.g.
+//
+//	{"level": "debug", "overrides": {"database": "warn", "cache": ""}}
+//
+// and responds with all of them.
+func (s *Server) SetLogLevelHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req config.LogLevels
+		if err := c.BodyParser(&req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid body")
+		}
+		// the error carries a stack trace, which is not for the client
+		if err := config.SetLogLevels(req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid log level, expected e.g. debug, info, warn or error")
+		}
+		levels := config.GetLogLevels()
+		s.log.InfoContext(c.UserContext(), "log levels changed",
+			slog.String("level", levels.Level),
+			slog.Any("overrides", levels.Overrides),
+		)
+		return c.JSON(levels)
+	}
+}
@@ -0,0 +1,21 @@
+package server
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/tlipoca9/asta/internal/buildinfo"
+	"github.com/tlipoca9/asta/internal/config"
+)
+
+// newBuildInfoCollector returns a gauge with a constant '1' value labeled by
+// what is running.
+func newBuildInfoCollector() prometheus.Collector {
+	buildInfo := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: config.MetricsNamespace,
+		Name:      "build_info",
+		Help:      "A metric with a constant '1' value labeled by the build and the active config profile.",
+	}, []string{"version", "commit", "go_version", "profile"})
+	info := buildinfo.Get()
+	buildInfo.WithLabelValues(info.Version, info.Commit, info.GoVersion, config.C.Profile).Set(1)
+	return buildInfo
+}
@@ -1,15 +1,9 @@
 package server
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
 	"log/slog"
-	"os"
-	"runtime/debug"
 	"strings"
 
-	"github.com/DataDog/gostackparse"
 	"github.com/gofiber/contrib/otelfiber"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/adaptor"
@@ -18,12 +12,13 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/monitor"
 	"github.com/gofiber/fiber/v2/middleware/pprof"
-	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/gofiber/fiber/v2/middleware/requestid"
 	"github.com/oklog/ulid/v2"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/tlipoca9/asta/internal/buildinfo"
 	"github.com/tlipoca9/asta/internal/config"
 	"github.com/tlipoca9/asta/pkg/fiberx"
 	"github.com/tlipoca9/asta/pkg/logx"
@@ -39,26 +34,19 @@ func (s *Server) RegisterMiddlewares() {
 		return c.Path() == "/healthz" || c.Path() == "/readyz" || strings.HasPrefix(c.Path(), "/debug")
 	}
 
-	// see https://docs.gofiber.io/api/middleware/recover
-	if config.C.Service.Console {
-		s.App.Use(recover.New(recover.Config{EnableStackTrace: true}))
-	} else {
-		s.App.Use(recover.New(recover.Config{
-			EnableStackTrace: true,
-			StackTraceHandler: func(_ *fiber.Ctx, e any) {
-				goroutines, _ := gostackparse.Parse(bytes.NewReader(debug.Stack()))
-				_, _ = fmt.Fprintln(os.Stderr, e)
-				_ = json.NewEncoder(os.Stderr).Encode(goroutines)
-			},
-		}))
-	}
+	// first of all, so that no panic of the middlewares below escapes, their
+	// requests have no span nor request id yet
+	panics := fiberx.NewPanicsCounter(config.MetricsNamespace)
+	prometheus.MustRegister(panics)
+	recoverConfig := fiberx.RecoverConfig{Logger: s.log, Panics: panics}
+	s.App.Use(fiberx.Recover(recoverConfig))
 
 	// see https://docs.gofiber.io/api/middleware/healthcheck
 	s.App.Use(
 		healthcheck.New(healthcheck.Config{
 			LivenessProbe:     func(_ *fiber.Ctx) bool { return true },
 			LivenessEndpoint:  "/healthz",
-			ReadinessProbe:    func(_ *fiber.Ctx) bool { return s.db.Health() && s.cache.Health() },
+			ReadinessProbe:    s.ReadinessProbe,
 			ReadinessEndpoint: "/readyz",
 		}),
 		otelfiber.Middleware(otelfiber.WithNext(commonNext)),
@@ -83,15 +71,36 @@ func (s *Server) RegisterMiddlewares() {
 		return c.Next()
 	})
 
-	if config.C.Service.Debug {
-		// see https://docs.gofiber.io/api/middleware/monitor
-		s.App.Get("/debug/metrics/ui", monitor.New())
-		// see https://docs.gofiber.io/api/middleware/pprof
-		s.App.Use(pprof.New())
-	}
+	s.App.Use(fiberx.DebugLog(func(c *fiber.Ctx) bool {
+		value := c.Get(config.Current().Service.DebugLog.Header)
+		if value == "" {
+			return false
+		}
+		if !config.TrustDebugLog(value, c.IP()) {
+			// at debug level, as anyone can send the header
+			s.log.DebugContext(c.UserContext(), "untrusted debug log request", slog.String("ip", c.IP()))
+			return false
+		}
+		s.log.InfoContext(c.UserContext(), "debug log requested", slog.String("ip", c.IP()))
+		return true
+	}))
+
+	// debug endpoints follow service.debug on config reload
+	debugNext := func(_ *fiber.Ctx) bool { return !config.Current().Service.Debug }
+	// see https://docs.gofiber.io/api/middleware/monitor
+	s.App.Get("/debug/metrics/ui", monitor.New(monitor.Config{Next: debugNext}))
+	// see https://docs.gofiber.io/api/middleware/pprof
+	s.App.Use(pprof.New(pprof.Config{Next: debugNext}))
+	s.App.Get("/debug/loglevel", s.AdminOnly(), s.GetLogLevelHandler())
+	s.App.Put("/debug/loglevel", s.AdminOnly(), s.SetLogLevelHandler())
 
 	// see https://prometheus.io/docs/guides/go-application
-	s.App.Get("/metrics", adaptor.HTTPHandler(promhttp.Handler()))
+	prometheus.MustRegister(newBuildInfoCollector())
+	// OpenMetrics is needed to expose the exemplars
+	s.App.Get("/metrics", adaptor.HTTPHandler(promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}),
+	)))
 
 	// see https://docs.gofiber.io/api/middleware/logger
 	if config.C.Service.Console {
@@ -100,15 +109,44 @@ func (s *Server) RegisterMiddlewares() {
 		s.App.Use(logger.New(fiberx.LoggerConfigJSON(commonNext)))
 	}
 
+	// after the logger, which handles the errors of the handlers itself
+	metrics := fiberx.NewMetrics(config.MetricsNamespace)
+	prometheus.MustRegister(metrics)
+	s.App.Use(metrics.Middleware(commonNext))
+
 	// see https://docs.gofiber.io/api/middleware/favicon
 	s.App.Use(favicon.New(favicon.Config{
 		Data: faviconFile,
 		URL:  "/favicon.ico",
 	}))
+
+	// panics of the handlers are recorded on the request span, counted and
+	// logged along with the request id, then go through the metrics and logger
+	s.App.Use(fiberx.Recover(recoverConfig))
+}
+
+// ReadinessProbe reports the state of the dependencies and the active config
+// profile, healthcheck keeps the body and only sets the status code.
+func (s *Server) ReadinessProbe(c *fiber.Ctx) bool {
+	db, cache := s.db.Health(), s.cache.Health()
+	ready := db && cache
+	_ = c.JSON(fiber.Map{
+		"ready":   ready,
+		"profile": config.C.Profile,
+		"checks":  fiber.Map{"database": db, "cache": cache},
+	})
+	return ready
 }
 
 func (s *Server) RegisterRoutes() {
 	s.App.Get("/", s.HelloWorldHandler())
+	s.App.Get("/version", s.VersionHandler())
+}
+
+func (s *Server) VersionHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.JSON(buildinfo.Get())
+	}
 }
 
 func (s *Server) HelloWorldHandler() fiber.Handler {
@@ -14,8 +14,6 @@ import (
 
 func Serve() error {
 	s := newServer()
-	s.RegisterMiddlewares()
-	s.RegisterRoutes()
 	config.DeferShutdown("server", s.ShutdownWithContext)
 	return errors.Wrap(s.Serve(config.C.Service.Addr), "start server failed")
 }
@@ -34,7 +32,7 @@ func newServer() *Server {
 			JSONEncoder: json.Marshal,
 			JSONDecoder: json.Unmarshal,
 		}),
-		log:   slog.Default(),
+		log:   config.Logger("server"),
 		db:    database.New(),
 		cache: cache.New(),
 	}
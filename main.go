@@ -2,27 +2,45 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"os"
 
 	"github.com/urfave/cli/v2"
 
+	"github.com/tlipoca9/asta/internal/buildinfo"
 	"github.com/tlipoca9/asta/internal/config"
 	"github.com/tlipoca9/asta/internal/server"
 )
 
 func main() {
-	log := slog.Default()
-
 	app := &cli.App{
-		Name: config.C.Service.Name,
+		Name:  "asta",
+		Flags: config.Flags(),
 		Commands: []*cli.Command{
 			{
 				Name: "serve",
+				Before: func(c *cli.Context) error {
+					if err := config.Init(c); err != nil {
+						return cli.Exit(err, 1)
+					}
+					return nil
+				},
 				Action: func(_ *cli.Context) error {
 					return server.Serve()
 				},
 			},
+			config.Command(),
+			{
+				Name:  "version",
+				Usage: "print the build info",
+				Action: func(c *cli.Context) error {
+					info := buildinfo.Get()
+					_, err := fmt.Fprintf(c.App.Writer, "%s\ncommit: %s\nbuild time: %s\nmodified: %t\ngo: %s %s\n",
+						info.Version, info.Commit, info.BuildTime, info.Modified, info.GoVersion, info.Platform)
+					return err
+				},
+			},
 		},
 	}
 
@@ -30,7 +48,7 @@ func main() {
 	go func() {
 		defer cancel()
 		if err := app.Run(os.Args); err != nil {
-			log.Error("app run failed", "error", err)
+			slog.Error("app run failed", "error", err)
 		}
 	}()
 
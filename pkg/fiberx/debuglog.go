@@ -0,0 +1,19 @@
+package fiberx
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/tlipoca9/asta/pkg/logx"
+)
+
+// DebugLog enables the debug logs of the requests which trusted accepts, see
+// logx.WithDebug and logx.VerifyDebugLog. Use it after the middlewares setting
+// up the user context.
+func DebugLog(trusted func(c *fiber.Ctx) bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if trusted(c) {
+			c.SetUserContext(logx.WithDebug(c.UserContext()))
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,124 @@
+package fiberx
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tlipoca9/errors"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RouteUnmatched labels the requests which matched no route, so that scans
+// of random paths do not blow up the cardinality.
+const RouteUnmatched = "unmatched"
+
+// Metrics are the RED metrics of http requests: rate, errors by status class
+// and duration, labeled by method and route template. Their names are prefixed
+// by namespace, e.g. "asta_http_requests_total".
+type Metrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight *prometheus.GaugeVec
+}
+
+func NewMetrics(namespace string) *Metrics {
+	return &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of http requests.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of the http requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "Number of http requests being served.",
+		}, []string{"method"}),
+	}
+}
+
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.requests.Describe(ch)
+	m.duration.Describe(ch)
+	m.inFlight.Describe(ch)
+}
+
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.requests.Collect(ch)
+	m.duration.Collect(ch)
+	m.inFlight.Collect(ch)
+}
+
+// Middleware records the requests not skipped by next. The requests of sampled
+// traces add their trace id as exemplar, which needs the OpenMetrics format.
+func (m *Metrics) Middleware(next func(*fiber.Ctx) bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if next != nil && next(c) {
+			return c.Next()
+		}
+
+		// the labels outlive the request, whose buffers fiber reuses
+		method := utils.CopyString(c.Method())
+		begin := time.Now()
+		inFlight := m.inFlight.WithLabelValues(method)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		err := c.Next()
+
+		route := c.Route().Path
+		if unmatched(c, err) {
+			route = RouteUnmatched
+		}
+		labels := prometheus.Labels{"method": method, "route": route, "status": statusClass(c, err)}
+		elapsed := time.Since(begin).Seconds()
+
+		var exemplar prometheus.Labels
+		if sc := trace.SpanContextFromContext(c.UserContext()); sc.IsSampled() {
+			exemplar = prometheus.Labels{"trace_id": sc.TraceID().String()}
+		}
+		requests, duration := m.requests.With(labels), m.duration.With(labels)
+		if exemplar != nil {
+			requests.(prometheus.ExemplarAdder).AddWithExemplar(1, exemplar)
+			duration.(prometheus.ExemplarObserver).ObserveWithExemplar(elapsed, exemplar)
+		} else {
+			requests.Inc()
+			duration.Observe(elapsed)
+		}
+		return err
+	}
+}
+
+// unmatched reports whether err is the error of fiber when no route matches,
+// c.Route() is then the last middleware.
+func unmatched(c *fiber.Ctx, err error) bool {
+	var fe *fiber.Error
+	if !errors.As(err, &fe) {
+		return false
+	}
+	return fe.Code == fiber.StatusMethodNotAllowed ||
+		fe.Code == fiber.StatusNotFound && strings.HasPrefix(fe.Message, "Cannot "+c.Method()+" ")
+}
+
+// statusClass returns "2xx", "4xx"... The status of a returned error is only
+// set by the error handler, after the middlewares.
+func statusClass(c *fiber.Ctx, err error) string {
+	code := c.Response().StatusCode()
+	if err != nil {
+		code = fiber.StatusInternalServerError
+		var fe *fiber.Error
+		if errors.As(err, &fe) {
+			code = fe.Code
+		}
+	}
+	return strconv.Itoa(code/100) + "xx"
+}
@@ -0,0 +1,103 @@
+package fiberx
+
+import (
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMetrics(t *testing.T) {
+	metrics := NewMetrics("test")
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+	})
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		if c.Get("X-Sampled") != "" {
+			c.SetUserContext(trace.ContextWithSpanContext(c.UserContext(), sc))
+		}
+		return c.Next()
+	})
+	app.Use(metrics.Middleware(func(c *fiber.Ctx) bool { return c.Path() == "/healthz" }))
+	app.Use(Recover(RecoverConfig{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}))
+	app.Get("/healthz", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	app.Get("/users/:id", func(c *fiber.Ctx) error { return c.SendString(c.Params("id")) })
+	app.Get("/created", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusCreated) })
+	app.Get("/invalid", func(_ *fiber.Ctx) error { return fiber.ErrBadRequest })
+	app.Get("/missing", func(_ *fiber.Ctx) error { return fiber.ErrNotFound })
+	app.Get("/panic", func(_ *fiber.Ctx) error { panic("boom") })
+
+	for _, req := range []struct {
+		method, path string
+		sampled      bool
+	}{
+		{fiber.MethodGet, "/users/42", true},
+		{fiber.MethodGet, "/users/43", false},
+		{fiber.MethodGet, "/created", false},
+		{fiber.MethodGet, "/invalid", false},
+		{fiber.MethodGet, "/missing", false},
+		{fiber.MethodGet, "/panic", false},
+		{fiber.MethodGet, "/nope", false},
+		{fiber.MethodGet, "/nope/again", false},
+		{fiber.MethodPost, "/users/42", false},
+		{fiber.MethodGet, "/healthz", false},
+	} {
+		r := httptest.NewRequest(req.method, req.path, nil)
+		if req.sampled {
+			r.Header.Set("X-Sampled", "1")
+		}
+		if _, err := app.Test(r); err != nil {
+			t.Fatalf("%s %s: %v", req.method, req.path, err)
+		}
+	}
+
+	tests := []struct {
+		method, route, status string
+		want                  float64
+	}{
+		// route templates, not paths
+		{fiber.MethodGet, "/users/:id", "2xx", 2},
+		{fiber.MethodGet, "/created", "2xx", 1},
+		{fiber.MethodGet, "/invalid", "4xx", 1},
+		// a 404 returned by a route keeps the route
+		{fiber.MethodGet, "/missing", "4xx", 1},
+		{fiber.MethodGet, "/panic", "5xx", 1},
+		{fiber.MethodGet, RouteUnmatched, "4xx", 2},
+		{fiber.MethodPost, RouteUnmatched, "4xx", 1},
+	}
+	for _, tt := range tests {
+		got := testutil.ToFloat64(metrics.requests.WithLabelValues(tt.method, tt.route, tt.status))
+		if got != tt.want {
+			t.Errorf("requests{%s %s %s} = %v, want %v", tt.method, tt.route, tt.status, got, tt.want)
+		}
+	}
+	if got := testutil.CollectAndCount(metrics.requests); got != len(tests) {
+		t.Errorf("requests series = %d, want %d", got, len(tests))
+	}
+	if got := testutil.CollectAndCount(metrics.duration); got != len(tests) {
+		t.Errorf("duration series = %d, want %d", got, len(tests))
+	}
+	if got := testutil.ToFloat64(metrics.inFlight.WithLabelValues(fiber.MethodGet)); got != 0 {
+		t.Errorf("in flight requests = %v, want 0", got)
+	}
+
+	var m dto.Metric
+	counter := metrics.requests.WithLabelValues(fiber.MethodGet, "/users/:id", "2xx").(prometheus.Metric)
+	if err := counter.Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	labels := m.GetCounter().GetExemplar().GetLabel()
+	if len(labels) != 1 || labels[0].GetName() != "trace_id" || labels[0].GetValue() != sc.TraceID().String() {
+		t.Errorf("exemplar labels = %v, want the trace id of the sampled request", labels)
+	}
+}
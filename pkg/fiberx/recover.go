@@ -0,0 +1,81 @@
+package fiberx
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type RecoverConfig struct {
+	// Next skips the middleware when it returns true.
+	Next func(c *fiber.Ctx) bool
+	// Logger logs the panics with the user context, so that the attributes
+	// added by logx.AppendCtx are included.
+	Logger *slog.Logger
+	// Panics, if set, is incremented with the method and route of each panic.
+	Panics *prometheus.CounterVec
+}
+
+// NewPanicsCounter returns the counter expected by RecoverConfig.Panics, named
+// like Metrics, e.g. "asta_http_panics_total".
+func NewPanicsCounter(namespace string) *prometheus.CounterVec {
+	return prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_panics_total",
+		Help:      "Number of panics recovered from http handlers.",
+	}, []string{"method", "route"})
+}
+
+// Recover turns the panics of the next handlers into 500 errors. Each panic is
+// recorded as an exception event on the span of the user context, which is
+// marked as failed, then counted and logged. Use it after the middlewares
+// setting up the user context.
+func Recover(cfg RecoverConfig) fiber.Handler {
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return func(c *fiber.Ctx) (err error) {
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+		defer func() {
+			e := recover()
+			if e == nil {
+				return
+			}
+			stack := string(debug.Stack())
+			ctx := c.UserContext()
+
+			span := trace.SpanFromContext(ctx)
+			span.AddEvent(semconv.ExceptionEventName, trace.WithAttributes(
+				semconv.ExceptionType(fmt.Sprintf("%T", e)),
+				semconv.ExceptionMessage(fmt.Sprint(e)),
+				semconv.ExceptionStacktrace(stack),
+				semconv.ExceptionEscaped(false),
+			))
+			span.SetStatus(codes.Error, fmt.Sprint("panic: ", e))
+
+			// the label and the log record outlive the request, whose
+			// buffers fiber reuses
+			method, route := utils.CopyString(c.Method()), c.Route().Path
+			if cfg.Panics != nil {
+				cfg.Panics.WithLabelValues(method, route).Inc()
+			}
+			cfg.Logger.ErrorContext(ctx, "panic recovered",
+				slog.String("method", method),
+				slog.String("route", route),
+				slog.String("error", fmt.Sprint(e)),
+				slog.String("stack", stack),
+			)
+			err = fiber.ErrInternalServerError
+		}()
+		return c.Next()
+	}
+}
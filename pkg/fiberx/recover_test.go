@@ -0,0 +1,84 @@
+package fiberx
+
+import (
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+func TestRecover(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	panics := NewPanicsCounter("test")
+	cfg := RecoverConfig{Logger: slog.New(slog.NewTextHandler(io.Discard, nil)), Panics: panics}
+
+	app := fiber.New()
+	app.Use(Recover(cfg))
+	app.Use(func(c *fiber.Ctx) error {
+		if c.Path() == "/middleware" {
+			panic("middleware")
+		}
+		// like otelfiber
+		ctx, span := tracer.Start(c.UserContext(), utils.CopyString(c.Path()))
+		defer span.End()
+		c.SetUserContext(ctx)
+		return c.Next()
+	})
+	app.Use(Recover(cfg))
+	app.Get("/users/:id", func(_ *fiber.Ctx) error { panic("handler") })
+	app.Get("/ok", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	for _, path := range []string{"/users/42", "/middleware", "/ok"} {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, path, nil))
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		want := fiber.StatusInternalServerError
+		if path == "/ok" {
+			want = fiber.StatusOK
+		}
+		if resp.StatusCode != want {
+			t.Errorf("GET %s: status = %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+
+	if got := testutil.ToFloat64(panics.WithLabelValues(fiber.MethodGet, "/users/:id")); got != 1 {
+		t.Errorf("panics of the handler = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(panics); got != 2 {
+		t.Errorf("panics series = %d, want 2", got)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("ended spans = %d, want 2", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "/users/42" {
+		t.Fatalf("span name = %q, want %q", span.Name(), "/users/42")
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("span status = %v, want %v", span.Status().Code, codes.Error)
+	}
+	events := span.Events()
+	if len(events) != 1 || events[0].Name != semconv.ExceptionEventName {
+		t.Fatalf("span events = %v, want one %q", events, semconv.ExceptionEventName)
+	}
+	for _, attr := range events[0].Attributes {
+		if attr.Key == semconv.ExceptionMessageKey && attr.Value.AsString() != "handler" {
+			t.Errorf("exception message = %q, want %q", attr.Value.AsString(), "handler")
+		}
+	}
+	if spans[1].Status().Code == codes.Error || len(spans[1].Events()) != 0 {
+		t.Errorf("span of /ok = %v %v, want no error", spans[1].Status(), spans[1].Events())
+	}
+}
@@ -0,0 +1,128 @@
+package gormx
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/tlipoca9/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const tracerName = "github.com/tlipoca9/asta/pkg/gormx"
+
+// TracingPlugin starts a client span for every statement run by gorm, as a
+// child of the span in the statement context. Use db.WithContext to link the
+// queries to a request.
+type TracingPlugin struct {
+	tp    trace.TracerProvider
+	attrs []attribute.KeyValue
+}
+
+type TracingOption func(*TracingPlugin)
+
+// WithTracerProvider uses tp instead of the global tracer provider.
+func WithTracerProvider(tp trace.TracerProvider) TracingOption {
+	return func(p *TracingPlugin) { p.tp = tp }
+}
+
+// WithAttributes adds attrs to every span, e.g. semconv.DBNamespace.
+func WithAttributes(attrs ...attribute.KeyValue) TracingOption {
+	return func(p *TracingPlugin) { p.attrs = append(p.attrs, attrs...) }
+}
+
+func NewTracingPlugin(opts ...TracingOption) *TracingPlugin {
+	p := &TracingPlugin{}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *TracingPlugin) Name() string {
+	return "gormx:tracing"
+}
+
+func (p *TracingPlugin) Initialize(db *gorm.DB) error {
+	tp := p.tp
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	tracer := tp.Tracer(tracerName)
+	attrs := append([]attribute.KeyValue{semconv.DBSystemKey.String(db.Dialector.Name())}, p.attrs...)
+
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register(p.Name()+":before_create", before(tracer, "create")),
+		cb.Create().After("gorm:create").Register(p.Name()+":after_create", after(attrs)),
+		cb.Query().Before("gorm:query").Register(p.Name()+":before_query", before(tracer, "query")),
+		cb.Query().After("gorm:query").Register(p.Name()+":after_query", after(attrs)),
+		cb.Update().Before("gorm:update").Register(p.Name()+":before_update", before(tracer, "update")),
+		cb.Update().After("gorm:update").Register(p.Name()+":after_update", after(attrs)),
+		cb.Delete().Before("gorm:delete").Register(p.Name()+":before_delete", before(tracer, "delete")),
+		cb.Delete().After("gorm:delete").Register(p.Name()+":after_delete", after(attrs)),
+		cb.Row().Before("gorm:row").Register(p.Name()+":before_row", before(tracer, "row")),
+		cb.Row().After("gorm:row").Register(p.Name()+":after_row", after(attrs)),
+		cb.Raw().Before("gorm:raw").Register(p.Name()+":before_raw", before(tracer, "raw")),
+		cb.Raw().After("gorm:raw").Register(p.Name()+":after_raw", after(attrs)),
+	)
+}
+
+func before(tracer trace.Tracer, name string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		db.Statement.Context, _ = tracer.Start(db.Statement.Context, "gorm."+name, trace.WithSpanKind(trace.SpanKindClient))
+	}
+}
+
+func after(attrs []attribute.KeyValue) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		span := trace.SpanFromContext(db.Statement.Context)
+		if !span.IsRecording() {
+			span.End()
+			return
+		}
+		defer span.End()
+
+		stmt := SanitizeSQL(db.Statement.SQL.String())
+		operation, _, _ := strings.Cut(stmt, " ")
+		operation = strings.ToUpper(operation)
+		span.SetAttributes(attrs...)
+		span.SetAttributes(
+			semconv.DBQueryText(stmt),
+			semconv.DBOperationName(operation),
+			attribute.Int64("db.rows_affected", db.RowsAffected),
+		)
+		if table := db.Statement.Table; table != "" {
+			span.SetAttributes(semconv.DBCollectionName(table))
+		}
+		if operation != "" {
+			span.SetName(strings.TrimSpace(operation + " " + db.Statement.Table))
+		}
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			span.RecordError(db.Error)
+			span.SetStatus(codes.Error, db.Error.Error())
+		}
+	}
+}
+
+var (
+	// hex and bit literals come first, x'1f' would otherwise leave the x
+	stringLiteral = regexp.MustCompile(`(?i)\b[xb]'[^']*'|\b0x[0-9a-f]+\b|\b0b[01]+\b|` +
+		`'(?:[^'\\]|\\.|'')*'|"(?:[^"\\]|\\.|"")*"`)
+	numericLiteral = regexp.MustCompile(`(?i)\b\d+(?:\.\d+)?(?:e[+-]?\d+)?\b`)
+	spaces         = regexp.MustCompile(`\s+`)
+)
+
+// SanitizeSQL replaces the literals of sql with "?", so that values inlined by
+// raw queries do not leak into spans. These are the strings in single or
+// double quotes, the hex and bit values and the numbers; identifiers quoted by
+// backticks are kept.
+func SanitizeSQL(sql string) string {
+	sql = stringLiteral.ReplaceAllString(sql, "?")
+	sql = numericLiteral.ReplaceAllString(sql, "?")
+	return strings.TrimSpace(spaces.ReplaceAllString(sql, " "))
+}
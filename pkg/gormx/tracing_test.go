@@ -0,0 +1,24 @@
+package gormx
+
+import "testing"
+
+func TestSanitizeSQL(t *testing.T) {
+	where := "SELECT * FROM users WHERE "
+	tests := []struct {
+		sql, want string
+	}{
+		{"SELECT * FROM `users` WHERE `id` = 42", "SELECT * FROM `users` WHERE `id` = ?"},
+		{where + "name = 'bob' AND score > 1.5", where + "name = ? AND score > ?"},
+		{where + `name = 'o''brien' OR name = 'a\'b'`, where + "name = ? OR name = ?"},
+		{where + `name = "bob" OR name = "say ""hi"" \"x\""`, where + "name = ? OR name = ?"},
+		{"SELECT * FROM t WHERE h = x'1F2e' OR h = X'00' OR h = 0x1f2E", "SELECT * FROM t WHERE h = ? OR h = ? OR h = ?"},
+		{"SELECT * FROM t WHERE f = b'101' OR f = B'1' OR f = 0b0110", "SELECT * FROM t WHERE f = ? OR f = ? OR f = ?"},
+		{"SELECT * FROM t2 WHERE v IN (1e3, 2.5E-2)", "SELECT * FROM t2 WHERE v IN (?, ?)"},
+		{"SELECT  *\n\tFROM `t1`  LIMIT 10 ", "SELECT * FROM `t1` LIMIT ?"},
+	}
+	for _, tt := range tests {
+		if got := SanitizeSQL(tt.sql); got != tt.want {
+			t.Errorf("SanitizeSQL(%q) = %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
@@ -9,11 +9,12 @@ func _() {
 	// Re-run the stringer command to generate them again.
 	var x [1]struct{}
 	_ = x[ContextKeyAttrs-1]
+	_ = x[ContextKeyDebug-2]
 }
 
-const _ContextKey_name = "attrs"
+const _ContextKey_name = "attrsdebug"
 
-var _ContextKey_index = [...]uint8{0, 5}
+var _ContextKey_index = [...]uint8{0, 5, 10}
 
 func (i ContextKey) String() string {
 	i -= 1
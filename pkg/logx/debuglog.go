@@ -0,0 +1,41 @@
+package logx
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignDebugLog returns a value enabling the debug logs, e.g. of a request,
+// until expires: "<unix time>.<hmac>".
+func SignDebugLog(key []byte, expires time.Time) string {
+	ts := strconv.FormatInt(expires.Unix(), 10)
+	return ts + "." + debugLogMAC(key, ts)
+}
+
+// VerifyDebugLog reports whether value was signed by SignDebugLog with key and
+// expires in at most maxTTL, so that leaked values are only usable for a while.
+func VerifyDebugLog(key []byte, value string, maxTTL time.Duration) bool {
+	ts, mac, ok := strings.Cut(value, ".")
+	if !ok || len(key) == 0 {
+		return false
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return false
+	}
+	ttl := time.Until(time.Unix(unix, 0))
+	if ttl <= 0 || ttl > maxTTL {
+		return false
+	}
+	return hmac.Equal([]byte(mac), []byte(debugLogMAC(key, ts)))
+}
+
+func debugLogMAC(key []byte, ts string) string {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(ts))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
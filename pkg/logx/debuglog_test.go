@@ -0,0 +1,39 @@
+package logx
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyDebugLog(t *testing.T) {
+	key := []byte("secret")
+	valid := SignDebugLog(key, time.Now().Add(10*time.Minute))
+	ts, mac, _ := strings.Cut(valid, ".")
+
+	tests := []struct {
+		name  string
+		key   []byte
+		value string
+		want  bool
+	}{
+		{"valid", key, valid, true},
+		{"wrong key", []byte("other"), valid, false},
+		{"no key", nil, SignDebugLog(nil, time.Now().Add(time.Minute)), false},
+		{"expired", key, SignDebugLog(key, time.Now().Add(-time.Second)), false},
+		{"beyond max ttl", key, SignDebugLog(key, time.Now().Add(2*time.Hour)), false},
+		{"extended expiry", key, "9999999999." + mac, false},
+		{"tampered mac", key, ts + "." + strings.ToUpper(mac), false},
+		{"no mac", key, ts, false},
+		{"invalid time", key, "soon." + mac, false},
+		{"enabled", key, "1", false},
+		{"empty", key, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyDebugLog(tt.key, tt.value, time.Hour); got != tt.want {
+				t.Errorf("VerifyDebugLog(%q, %q) = %v, want %v", tt.key, tt.value, got, tt.want)
+			}
+		})
+	}
+}
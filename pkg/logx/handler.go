@@ -0,0 +1,141 @@
+package logx
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	"github.com/tlipoca9/errors"
+)
+
+// FanoutHandler passes the records to each of its handlers which is enabled
+// for their level.
+type FanoutHandler struct {
+	handlers []slog.Handler
+}
+
+func NewFanoutHandler(hs ...slog.Handler) slog.Handler {
+	if len(hs) == 1 {
+		return hs[0]
+	}
+	return FanoutHandler{handlers: hs}
+}
+
+func (h FanoutHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	for _, hh := range h.handlers {
+		if hh.Enabled(ctx, l) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle passes a clone of r to each handler, so that they may add attributes
+// to it, and joins their errors.
+func (h FanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, hh := range h.handlers {
+		if hh.Enabled(ctx, r.Level) {
+			errs = append(errs, hh.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h FanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	hs := make([]slog.Handler, 0, len(h.handlers))
+	for _, hh := range h.handlers {
+		hs = append(hs, hh.WithAttrs(attrs))
+	}
+	return FanoutHandler{handlers: hs}
+}
+
+func (h FanoutHandler) WithGroup(name string) slog.Handler {
+	hs := make([]slog.Handler, 0, len(h.handlers))
+	for _, hh := range h.handlers {
+		hs = append(hs, hh.WithGroup(name))
+	}
+	return FanoutHandler{handlers: hs}
+}
+
+// LevelHandler drops the records below level, for handlers which have no
+// level option of their own.
+type LevelHandler struct {
+	slog.Handler
+	level slog.Leveler
+}
+
+func NewLevelHandler(level slog.Leveler, h slog.Handler) slog.Handler {
+	return LevelHandler{Handler: h, level: level}
+}
+
+func (h LevelHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return l >= h.level.Level() && h.Handler.Enabled(ctx, l)
+}
+
+func (h LevelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return LevelHandler{Handler: h.Handler.WithAttrs(attrs), level: h.level}
+}
+
+func (h LevelHandler) WithGroup(name string) slog.Handler {
+	return LevelHandler{Handler: h.Handler.WithGroup(name), level: h.level}
+}
+
+// ReplaceAttrHandler rewrites the attributes like slog.HandlerOptions.ReplaceAttr,
+// for handlers which have no such option. An attribute replaced by the zero
+// Attr is dropped.
+type ReplaceAttrHandler struct {
+	slog.Handler
+	replace func(groups []string, a slog.Attr) slog.Attr
+	groups  []string
+}
+
+func NewReplaceAttrHandler(h slog.Handler, replace func(groups []string, a slog.Attr) slog.Attr) slog.Handler {
+	return ReplaceAttrHandler{Handler: h, replace: replace}
+}
+
+func (h ReplaceAttrHandler) Handle(ctx context.Context, r slog.Record) error {
+	nr := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		if a = h.replaceAttr(h.groups, a); !a.Equal(slog.Attr{}) {
+			nr.AddAttrs(a)
+		}
+		return true
+	})
+	return h.Handler.Handle(ctx, nr)
+}
+
+func (h ReplaceAttrHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	replaced := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		if a = h.replaceAttr(h.groups, a); !a.Equal(slog.Attr{}) {
+			replaced = append(replaced, a)
+		}
+	}
+	return ReplaceAttrHandler{Handler: h.Handler.WithAttrs(replaced), replace: h.replace, groups: h.groups}
+}
+
+func (h ReplaceAttrHandler) WithGroup(name string) slog.Handler {
+	groups := append(slices.Clip(h.groups), name)
+	return ReplaceAttrHandler{Handler: h.Handler.WithGroup(name), replace: h.replace, groups: groups}
+}
+
+// replaceAttr replaces the attributes in groups recursively, the groups
+// themselves are kept as is and inline
+// groups add no key to groups.
+func (h ReplaceAttrHandler) replaceAttr(groups []string, a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() != slog.KindGroup {
+		return h.replace(groups, a)
+	}
+	if a.Key != "" {
+		groups = append(slices.Clip(groups), a.Key)
+	}
+	attrs := make([]any, 0, len(a.Value.Group()))
+	for _, ga := range a.Value.Group() {
+		if ga = h.replaceAttr(groups, ga); !ga.Equal(slog.Attr{}) {
+			attrs = append(attrs, ga)
+		}
+	}
+	return slog.Group(a.Key, attrs...)
+}
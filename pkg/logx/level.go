@@ -0,0 +1,96 @@
+package logx
+
+import (
+	"log/slog"
+	"maps"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Levels are the levels of named loggers, which can be changed at runtime. A
+// logger named "a.b" takes the level of "a.b" if overridden, else of "a",
+// else the default level.
+type Levels struct {
+	def       slog.LevelVar
+	mu        sync.Mutex
+	overrides atomic.Pointer[map[string]slog.Level]
+}
+
+func (l *Levels) Default() slog.Level {
+	return l.def.Level()
+}
+
+func (l *Levels) SetDefault(level slog.Level) {
+	l.def.Set(level)
+}
+
+// Overrides returns a copy of the levels of the named loggers.
+func (l *Levels) Overrides() map[string]slog.Level {
+	if m := l.overrides.Load(); m != nil {
+		return maps.Clone(*m)
+	}
+	return map[string]slog.Level{}
+}
+
+// SetOverrides replaces all the levels of the named loggers.
+func (l *Levels) SetOverrides(overrides map[string]slog.Level) {
+	m := maps.Clone(overrides)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.overrides.Store(&m)
+}
+
+// Set overrides the level of the loggers named name or prefixed by "name.".
+func (l *Levels) Set(name string, level slog.Level) {
+	l.Update(func(m map[string]slog.Level) { m[name] = level })
+}
+
+// Unset makes the loggers named name follow their parent again.
+func (l *Levels) Unset(name string) {
+	l.Update(func(m map[string]slog.Level) { delete(m, name) })
+}
+
+// Update changes the levels of the named loggers in place with fn. Updates are
+// serialized, so that concurrent ones do not undo each other.
+func (l *Levels) Update(fn func(overrides map[string]slog.Level)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	m := map[string]slog.Level{}
+	if old := l.overrides.Load(); old != nil {
+		m = maps.Clone(*old)
+	}
+	fn(m)
+	l.overrides.Store(&m)
+}
+
+// Of returns the level of the logger named name, "" is the default logger.
+func (l *Levels) Of(name string) slog.Level {
+	if m := l.overrides.Load(); m != nil && len(*m) > 0 {
+		for n := name; n != ""; {
+			if level, ok := (*m)[n]; ok {
+				return level
+			}
+			i := strings.LastIndexByte(n, '.')
+			if i < 0 {
+				break
+			}
+			n = n[:i]
+		}
+	}
+	return l.def.Level()
+}
+
+// Leveler returns the slog.Leveler of the logger named name.
+func (l *Levels) Leveler(name string) slog.Leveler {
+	return namedLevel{levels: l, name: name}
+}
+
+type namedLevel struct {
+	levels *Levels
+	name   string
+}
+
+func (n namedLevel) Level() slog.Level {
+	return n.levels.Of(n.name)
+}
@@ -15,6 +15,7 @@ type ContextKey int
 //go:generate stringer -type=ContextKey -output=contextkey.gen.go -linecomment
 const (
 	ContextKeyAttrs ContextKey = iota + 1 // attrs
+	ContextKeyDebug                       // debug
 )
 
 type ContextHandler struct {
@@ -35,6 +36,20 @@ func (h ContextHandler) Handle(ctx context.Context, r slog.Record) error {
 	return h.Handler.Handle(ctx, r)
 }
 
+func (h ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return ContextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h ContextHandler) WithGroup(name string) slog.Handler {
+	return ContextHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// Enabled also enables the debug records of the contexts returned by WithDebug,
+// whatever the level of the underlying handler.
+func (h ContextHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return l >= slog.LevelDebug && IsDebug(ctx) || h.Handler.Enabled(ctx, l)
+}
+
 // AppendCtx adds a slog attribute to the provided context so that it will be
 // included in any Record created with such context
 func AppendCtx(parent context.Context, attr ...slog.Attr) context.Context {
@@ -49,6 +64,21 @@ func AppendCtx(parent context.Context, attr ...slog.Attr) context.Context {
 	return context.WithValue(parent, ContextKeyAttrs, attr)
 }
 
+// WithDebug enables the debug records logged with the returned context, e.g.
+// to investigate a single request.
+func WithDebug(parent context.Context) context.Context {
+	if parent == nil {
+		parent = context.Background()
+	}
+	return context.WithValue(parent, ContextKeyDebug, true)
+}
+
+// IsDebug reports whether ctx was returned by WithDebug.
+func IsDebug(ctx context.Context) bool {
+	debug, _ := ctx.Value(ContextKeyDebug).(bool)
+	return debug
+}
+
 func JSON(key string, val any) slog.Attr {
 	ret := make([]map[string]any, 0)
 	if v, ok := val.([]map[string]any); ok {
@@ -0,0 +1,143 @@
+package logx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tlipoca9/errors"
+)
+
+// SyslogFacilities are the facilities of the syslog messages by name.
+var SyslogFacilities = map[string]int{
+	"user":   1,
+	"daemon": 3,
+	"local0": 16,
+	"local1": 17,
+	"local2": 18,
+	"local3": 19,
+	"local4": 20,
+	"local5": 21,
+	"local6": 22,
+	"local7": 23,
+}
+
+// SyslogHandler sends the records formatted by its handler to a syslog server
+// as RFC 3164 messages, with the severity of their level.
+type SyslogHandler struct {
+	slog.Handler
+	conn *syslogConn
+}
+
+type syslogConn struct {
+	network  string
+	addr     string
+	facility int
+	tag      string
+	hostname string
+
+	mu   sync.Mutex
+	buf  bytes.Buffer
+	conn net.Conn
+}
+
+// NewSyslogHandler dials addr, e.g. "localhost:514" over udp or "/dev/log"
+// over unixgram. newHandler returns the handler formatting the records to w,
+// such as slog.NewJSONHandler.
+func NewSyslogHandler(
+	network, addr string,
+	facility int,
+	tag string,
+	newHandler func(w io.Writer) slog.Handler,
+) (*SyslogHandler, error) {
+	c := &syslogConn{network: network, addr: addr, facility: facility, tag: tag}
+	c.hostname, _ = os.Hostname()
+	if err := c.dial(); err != nil {
+		return nil, err
+	}
+	return &SyslogHandler{Handler: newHandler(&c.buf), conn: c}, nil
+}
+
+func (h *SyslogHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.conn.mu.Lock()
+	defer h.conn.mu.Unlock()
+
+	h.conn.buf.Reset()
+	if err := h.Handler.Handle(ctx, r); err != nil {
+		return err
+	}
+	return h.conn.send(r.Time, severity(r.Level), bytes.TrimSuffix(h.conn.buf.Bytes(), []byte("\n")))
+}
+
+func (h *SyslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &SyslogHandler{Handler: h.Handler.WithAttrs(attrs), conn: h.conn}
+}
+
+func (h *SyslogHandler) WithGroup(name string) slog.Handler {
+	return &SyslogHandler{Handler: h.Handler.WithGroup(name), conn: h.conn}
+}
+
+// Close closes the connection, the handler must not be used afterwards.
+func (h *SyslogHandler) Close() error {
+	h.conn.mu.Lock()
+	defer h.conn.mu.Unlock()
+	if h.conn.conn == nil {
+		return nil
+	}
+	err := h.conn.conn.Close()
+	h.conn.conn = nil
+	return err
+}
+
+func (c *syslogConn) dial() error {
+	conn, err := net.Dial(c.network, c.addr)
+	if err != nil {
+		return errors.Wrapf(err, "dial syslog %s://%s failed", c.network, c.addr)
+	}
+	c.conn = conn
+	return nil
+}
+
+// send writes the message, dialing again once if the connection broke, e.g.
+// when the local syslog daemon restarted.
+func (c *syslogConn) send(t time.Time, severity int, msg []byte) error {
+	if c.conn == nil {
+		return os.ErrClosed
+	}
+	pri := c.facility*8 + severity
+	// RFC 3164 timestamps are local time without year, and local daemons add
+	// the hostname themselves
+	header := fmt.Sprintf("<%d>%s", pri, t.Local().Format(time.Stamp))
+	if c.network != "unix" && c.network != "unixgram" {
+		header += " " + c.hostname
+	}
+	line := fmt.Sprintf("%s %s[%d]: %s\n", header, c.tag, os.Getpid(), msg)
+	if _, err := io.WriteString(c.conn, line); err == nil {
+		return nil
+	}
+	_ = c.conn.Close()
+	if err := c.dial(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(c.conn, line)
+	return errors.Wrap(err, "write syslog failed")
+}
+
+func severity(l slog.Level) int {
+	switch {
+	case l >= slog.LevelError:
+		return 3
+	case l >= slog.LevelWarn:
+		return 4
+	case l >= slog.LevelInfo:
+		return 6
+	default:
+		return 7
+	}
+}
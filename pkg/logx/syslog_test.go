@@ -0,0 +1,55 @@
+package logx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSyslogHandler(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	newHandler := func(w io.Writer) slog.Handler {
+		return slog.NewTextHandler(w, &slog.HandlerOptions{
+			ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		})
+	}
+	h, err := NewSyslogHandler("udp", conn.LocalAddr().String(), SyslogFacilities["local0"], "asta", newHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	ts := time.Date(2024, 3, 5, 6, 7, 8, 0, time.Local)
+	if err := h.Handle(context.Background(), slog.NewRecord(ts, slog.LevelWarn, "disk full", 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// local0 * 8 + warning
+	hostname, _ := os.Hostname()
+	want := fmt.Sprintf("<132>Mar  5 06:07:08 %s asta[%d]: level=WARN msg=\"disk full\"\n", hostname, os.Getpid())
+	if got := string(buf[:n]); got != want {
+		t.Errorf("message = %q, want %q", got, want)
+	}
+}
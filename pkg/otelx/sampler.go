@@ -0,0 +1,171 @@
+package otelx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// now is replaced by the tests to refill the tokens of RateLimitedSampler.
+var now = time.Now
+
+// Rule applies Sampler to the spans whose name matches Route, exactly or as a
+// prefix if Route ends with "*". Samplers only see what is known at span start,
+// so for otelfiber spans Route matches the raw request path such as
+// "/users/42", not the route template "/users/:id".
+type Rule struct {
+	Route   string
+	Sampler sdktrace.Sampler
+}
+
+func (r Rule) match(p sdktrace.SamplingParameters) bool {
+	return matchRoute(r.Route, p.Name)
+}
+
+func matchRoute(pattern, s string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(s, prefix)
+	}
+	return pattern == s
+}
+
+type ruleSampler struct {
+	rules    []Rule
+	fallback sdktrace.Sampler
+}
+
+// RuleSampler samples a span with the first matching rule, or with fallback
+// if none matches. Rules apply to every span regardless of its parent.
+func RuleSampler(rules []Rule, fallback sdktrace.Sampler) sdktrace.Sampler {
+	return ruleSampler{rules: rules, fallback: fallback}
+}
+
+func (s ruleSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	for _, r := range s.rules {
+		if r.match(p) {
+			return r.Sampler.ShouldSample(p)
+		}
+	}
+	return s.fallback.ShouldSample(p)
+}
+
+func (s ruleSampler) Description() string {
+	rules := make([]string, 0, len(s.rules))
+	for _, r := range s.rules {
+		rules = append(rules, r.Route+":"+r.Sampler.Description())
+	}
+	return fmt.Sprintf("RuleSampler{rules:[%s],fallback:%s}", strings.Join(rules, ","), s.fallback.Description())
+}
+
+type rateLimitedSampler struct {
+	perSecond float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// RateLimitedSampler samples at most perSecond spans per second, with bursts
+// of up to one second worth of spans.
+func RateLimitedSampler(perSecond float64) sdktrace.Sampler {
+	return &rateLimitedSampler{perSecond: perSecond, tokens: max(perSecond, 1), last: now()}
+}
+
+func (s *rateLimitedSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	ret := sdktrace.SamplingResult{
+		Decision:   sdktrace.Drop,
+		Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+	}
+	if s.allow(now()) {
+		ret.Decision = sdktrace.RecordAndSample
+	}
+	return ret
+}
+
+func (s *rateLimitedSampler) allow(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = min(s.tokens+now.Sub(s.last).Seconds()*s.perSecond, max(s.perSecond, 1))
+	s.last = now
+	if s.tokens < 1 {
+		return false
+	}
+	s.tokens--
+	return true
+}
+
+func (s *rateLimitedSampler) Description() string {
+	return fmt.Sprintf("RateLimitedSampler{%g}", s.perSecond)
+}
+
+type recordDroppedSampler struct {
+	sdktrace.Sampler
+}
+
+// RecordDroppedSampler records the spans s drops without sampling them, so
+// that a span processor such as ErrorSpanProcessor can still export them.
+func RecordDroppedSampler(s sdktrace.Sampler) sdktrace.Sampler {
+	return recordDroppedSampler{Sampler: s}
+}
+
+func (s recordDroppedSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	ret := s.Sampler.ShouldSample(p)
+	if ret.Decision == sdktrace.Drop {
+		ret.Decision = sdktrace.RecordOnly
+	}
+	return ret
+}
+
+func (s recordDroppedSampler) Description() string {
+	return "RecordDropped{" + s.Sampler.Description() + "}"
+}
+
+// ErrorSpanProcessor passes the sampled spans to next, along with the recorded
+// but unsampled spans which ended with an error status. It is meant to be used
+// with RecordDroppedSampler to always export failures.
+type ErrorSpanProcessor struct {
+	next sdktrace.SpanProcessor
+}
+
+func NewErrorSpanProcessor(next sdktrace.SpanProcessor) *ErrorSpanProcessor {
+	return &ErrorSpanProcessor{next: next}
+}
+
+func (p *ErrorSpanProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	if s.SpanContext().IsSampled() {
+		p.next.OnStart(parent, s)
+	}
+}
+
+func (p *ErrorSpanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	switch {
+	case s.SpanContext().IsSampled():
+		p.next.OnEnd(s)
+	case s.Status().Code == codes.Error:
+		p.next.OnEnd(sampledSpan{ReadOnlySpan: s})
+	}
+}
+
+func (p *ErrorSpanProcessor) Shutdown(ctx context.Context) error {
+	return p.next.Shutdown(ctx)
+}
+
+func (p *ErrorSpanProcessor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
+
+// sampledSpan marks an unsampled span as sampled, as exporters skip the others.
+type sampledSpan struct {
+	sdktrace.ReadOnlySpan
+}
+
+func (s sampledSpan) SpanContext() trace.SpanContext {
+	sc := s.ReadOnlySpan.SpanContext()
+	return sc.WithTraceFlags(sc.TraceFlags().WithSampled(true))
+}
@@ -0,0 +1,177 @@
+package otelx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// parent returns a context with a remote parent span, sampled or not.
+func parent(sampled bool) context.Context {
+	var flags trace.TraceFlags
+	if sampled {
+		flags = trace.FlagsSampled
+	}
+	return trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: flags,
+		Remote:     true,
+	}))
+}
+
+func TestRuleSampler(t *testing.T) {
+	sampler := RuleSampler([]Rule{
+		{Route: "/users/admin", Sampler: sdktrace.NeverSample()},
+		{Route: "/users/*", Sampler: sdktrace.AlwaysSample()},
+		{Route: "/ping", Sampler: sdktrace.AlwaysSample()},
+	}, sdktrace.ParentBased(sdktrace.NeverSample()))
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want sdktrace.SamplingDecision
+	}{
+		{"/users/42", context.Background(), sdktrace.RecordAndSample},
+		{"/users/", context.Background(), sdktrace.RecordAndSample},
+		{"/users/admin", context.Background(), sdktrace.Drop},
+		{"/users", context.Background(), sdktrace.Drop},
+		{"/ping", context.Background(), sdktrace.RecordAndSample},
+		{"/ping/x", context.Background(), sdktrace.Drop},
+		{"/other", context.Background(), sdktrace.Drop},
+		// the fallback follows the parent, the rules do not
+		{"/other", parent(true), sdktrace.RecordAndSample},
+		{"/other", parent(false), sdktrace.Drop},
+		{"/users/admin", parent(true), sdktrace.Drop},
+		{"/users/42", parent(false), sdktrace.RecordAndSample},
+	}
+	for _, tt := range tests {
+		p := sdktrace.SamplingParameters{ParentContext: tt.ctx, TraceID: trace.TraceID{1}, Name: tt.name}
+		if got := sampler.ShouldSample(p).Decision; got != tt.want {
+			t.Errorf("ShouldSample(%q, sampled parent %v) = %v, want %v",
+				tt.name, trace.SpanContextFromContext(tt.ctx).IsSampled(), got, tt.want)
+		}
+	}
+}
+
+func TestRateLimitedSampler(t *testing.T) {
+	clock := time.Unix(0, 0)
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { now = time.Now })
+
+	// a step advances the clock, then samples that many spans
+	type step struct {
+		advance     time.Duration
+		spans, want int
+	}
+	tests := []struct {
+		perSecond float64
+		steps     []step
+	}{
+		{perSecond: 2, steps: []step{
+			{0, 3, 2},
+			{500 * time.Millisecond, 2, 1},
+			{time.Minute, 5, 2},
+		}},
+		{perSecond: 0.5, steps: []step{
+			{0, 2, 1},
+			{time.Second, 1, 0},
+			{time.Second, 2, 1},
+		}},
+	}
+	for _, tt := range tests {
+		sampler := RateLimitedSampler(tt.perSecond)
+		for i, step := range tt.steps {
+			clock = clock.Add(step.advance)
+			got := 0
+			for range step.spans {
+				p := sdktrace.SamplingParameters{ParentContext: context.Background(), Name: "span"}
+				if sampler.ShouldSample(p).Decision == sdktrace.RecordAndSample {
+					got++
+				}
+			}
+			if got != step.want {
+				t.Errorf("%g/s, step %d: sampled %d of %d spans, want %d", tt.perSecond, i, got, step.spans, step.want)
+			}
+		}
+	}
+}
+
+func TestParentBasedRateLimitedSampler(t *testing.T) {
+	clock := time.Unix(0, 0)
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { now = time.Now })
+
+	// as for otel.sampler.type = "rate_limited", the limit only applies to roots
+	sampler := sdktrace.ParentBased(RateLimitedSampler(1))
+	tests := []struct {
+		ctx  context.Context
+		want sdktrace.SamplingDecision
+	}{
+		{context.Background(), sdktrace.RecordAndSample},
+		{context.Background(), sdktrace.Drop},
+		{parent(true), sdktrace.RecordAndSample},
+		{parent(false), sdktrace.Drop},
+	}
+	for i, tt := range tests {
+		p := sdktrace.SamplingParameters{ParentContext: tt.ctx, Name: "span"}
+		if got := sampler.ShouldSample(p).Decision; got != tt.want {
+			t.Errorf("span %d: ShouldSample = %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestRecordDroppedSampler(t *testing.T) {
+	tests := []struct {
+		sampler sdktrace.Sampler
+		want    sdktrace.SamplingDecision
+	}{
+		{sdktrace.AlwaysSample(), sdktrace.RecordAndSample},
+		{sdktrace.NeverSample(), sdktrace.RecordOnly},
+	}
+	for _, tt := range tests {
+		p := sdktrace.SamplingParameters{ParentContext: context.Background(), Name: "span"}
+		if got := RecordDroppedSampler(tt.sampler).ShouldSample(p).Decision; got != tt.want {
+			t.Errorf("RecordDroppedSampler(%s) = %v, want %v", tt.sampler.Description(), got, tt.want)
+		}
+	}
+}
+
+func TestErrorSpanProcessor(t *testing.T) {
+	tests := []struct {
+		sampler sdktrace.Sampler
+		failed  bool
+		want    bool
+	}{
+		{sdktrace.AlwaysSample(), false, true},
+		{sdktrace.AlwaysSample(), true, true},
+		{sdktrace.NeverSample(), false, false},
+		{sdktrace.NeverSample(), true, true},
+	}
+	for _, tt := range tests {
+		recorder := tracetest.NewSpanRecorder()
+		tp := sdktrace.NewTracerProvider(
+			sdktrace.WithSampler(RecordDroppedSampler(tt.sampler)),
+			sdktrace.WithSpanProcessor(NewErrorSpanProcessor(recorder)),
+		)
+		_, span := tp.Tracer("test").Start(context.Background(), "span")
+		if tt.failed {
+			span.SetStatus(codes.Error, "failed")
+		}
+		span.End()
+
+		ended := recorder.Ended()
+		if got := len(ended) == 1; got != tt.want {
+			t.Errorf("%s, failed %v: exported %v, want %v", tt.sampler.Description(), tt.failed, got, tt.want)
+			continue
+		}
+		if tt.want && !ended[0].SpanContext().IsSampled() {
+			t.Errorf("%s, failed %v: exported span is not sampled", tt.sampler.Description(), tt.failed)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package rotatex
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tlipoca9/errors"
+)
+
+const timeFormat = "2006-01-02T15-04-05.000"
+
+// now is replaced by the tests to pick the names of the rotated files.
+var now = time.Now
+
+type Options struct {
+	// MaxSize rotates the file before it grows over MaxSize bytes, 0 disables it.
+	MaxSize int64
+	// Interval rotates the file once it is older than Interval, 0 disables it.
+	Interval time.Duration
+	// MaxFiles is the number of rotated files kept, 0 keeps all of them.
+	MaxFiles int
+	// Compress gzips the rotated files.
+	Compress bool
+}
+
+// Writer appends to a file and rotates it to "<name>-<time><ext>" according
+// to its Options. Rotated files are compressed and pruned in the background.
+type Writer struct {
+	path string
+	opts Options
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// New opens path for appending, creating it and its directory if needed.
+func New(path string, opts Options) (*Writer, error) {
+	w := &Writer{path: path, opts: opts}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, errors.Wrap(err, "create log dir failed")
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if w.file == nil {
+		// a failed rotation left no file open
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	var rotateErr error
+	if w.shouldRotate(int64(len(p))) {
+		// write to the current file anyway if the rotation failed
+		if rotateErr = w.rotate(); w.file == nil {
+			return 0, rotateErr
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, errors.Join(rotateErr, err)
+}
+
+// Rotate rotates the file regardless of the Options.
+func (w *Writer) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	return w.rotate()
+}
+
+// Close closes the file and waits for the background compression.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+	w.wg.Wait()
+	return err
+}
+
+func (w *Writer) shouldRotate(n int64) bool {
+	if w.size == 0 {
+		return false
+	}
+	if w.opts.MaxSize > 0 && w.size+n > w.opts.MaxSize {
+		return true
+	}
+	return w.opts.Interval > 0 && now().Sub(w.openedAt) >= w.opts.Interval
+}
+
+func (w *Writer) open() error {
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return errors.Wrap(err, "open file failed")
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return errors.Wrap(err, "stat file failed")
+	}
+	w.file, w.size, w.openedAt = f, info.Size(), now()
+	return nil
+}
+
+// rotate renames the file and opens a new one. If the rename fails, it keeps
+// appending to the current file, so that a failed rotation loses no writes.
+func (w *Writer) rotate() error {
+	if w.file != nil {
+		err := w.file.Close()
+		w.file = nil
+		if err != nil {
+			return errors.Join(errors.Wrap(err, "close file failed"), w.open())
+		}
+	}
+
+	ext := filepath.Ext(w.path)
+	rotated := strings.TrimSuffix(w.path, ext) + "-" + now().Format(timeFormat) + ext
+	if err := os.Rename(w.path, rotated); err != nil && !os.IsNotExist(err) {
+		return errors.Join(errors.Wrap(err, "rename file failed"), w.open())
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		if w.opts.Compress {
+			_ = compress(rotated)
+		}
+		_ = w.prune()
+	}()
+	return nil
+}
+
+// rotated returns the rotated files, oldest first.
+func (w *Writer) rotated() ([]string, error) {
+	ext := filepath.Ext(w.path)
+	prefix := filepath.Base(strings.TrimSuffix(w.path, ext)) + "-"
+	entries, err := os.ReadDir(filepath.Dir(w.path))
+	if err != nil {
+		return nil, err
+	}
+	var ret []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		ts, ok := strings.CutSuffix(strings.TrimSuffix(name, ".gz"), ext)
+		if !ok {
+			continue
+		}
+		if _, err := time.Parse(timeFormat, strings.TrimPrefix(ts, prefix)); err != nil {
+			continue
+		}
+		ret = append(ret, filepath.Join(filepath.Dir(w.path), name))
+	}
+	// the timestamps sort lexically
+	slices.Sort(ret)
+	return ret, nil
+}
+
+func (w *Writer) prune() error {
+	if w.opts.MaxFiles <= 0 {
+		return nil
+	}
+	files, err := w.rotated()
+	if err != nil || len(files) <= w.opts.MaxFiles {
+		return err
+	}
+	var errs []error
+	for _, f := range files[:len(files)-w.opts.MaxFiles] {
+		errs = append(errs, os.Remove(f))
+	}
+	return errors.Join(errs...)
+}
+
+func compress(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		_ = zw.Close()
+		_ = dst.Close()
+		_ = os.Remove(path + ".gz")
+		return err
+	}
+	if err := errors.Join(zw.Close(), dst.Close()); err != nil {
+		_ = os.Remove(path + ".gz")
+		return err
+	}
+	return os.Remove(path)
+}
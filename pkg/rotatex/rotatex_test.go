@@ -0,0 +1,213 @@
+package rotatex
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock makes now return t, advanced by step on every call, so that the
+// rotated files get distinct names.
+func fakeClock(t *testing.T, step time.Duration) {
+	t.Helper()
+	cur := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	now = func() time.Time {
+		cur = cur.Add(step)
+		return cur
+	}
+	t.Cleanup(func() { now = time.Now })
+}
+
+func newWriter(t *testing.T, opts Options) (*Writer, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+	w, err := New(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = w.Close() })
+	return w, path
+}
+
+func write(t *testing.T, w *Writer, s string) {
+	t.Helper()
+	if _, err := io.WriteString(w, s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func rotatedFiles(t *testing.T, w *Writer) []string {
+	t.Helper()
+	files, err := w.rotated()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestWriterMaxSize(t *testing.T) {
+	fakeClock(t, time.Millisecond)
+	w, path := newWriter(t, Options{MaxSize: 10})
+
+	write(t, w, "first\n")
+	write(t, w, "second\n")
+	write(t, w, "third\n")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files := rotatedFiles(t, w)
+	if len(files) != 2 {
+		t.Fatalf("rotated files = %v, want 2", files)
+	}
+	if got := readFile(t, files[0]); got != "first\n" {
+		t.Errorf("oldest rotated file = %q, want %q", got, "first\n")
+	}
+	if got := readFile(t, files[1]); got != "second\n" {
+		t.Errorf("newest rotated file = %q, want %q", got, "second\n")
+	}
+	if got := readFile(t, path); got != "third\n" {
+		t.Errorf("current file = %q, want %q", got, "third\n")
+	}
+}
+
+func TestWriterInterval(t *testing.T) {
+	fakeClock(t, time.Minute)
+	w, path := newWriter(t, Options{Interval: 30 * time.Second})
+
+	// each call of now advances a minute
+	write(t, w, "first\n")  // the file is empty
+	write(t, w, "second\n") // the file was opened a minute ago
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files := rotatedFiles(t, w)
+	if len(files) != 1 {
+		t.Fatalf("rotated files = %v, want 1", files)
+	}
+	if got := readFile(t, files[0]); got != "first\n" {
+		t.Errorf("rotated file = %q, want %q", got, "first\n")
+	}
+	if got := readFile(t, path); got != "second\n" {
+		t.Errorf("current file = %q, want %q", got, "second\n")
+	}
+}
+
+func TestWriterMaxFiles(t *testing.T) {
+	fakeClock(t, time.Millisecond)
+	w, path := newWriter(t, Options{MaxFiles: 2})
+
+	for _, s := range []string{"1\n", "2\n", "3\n", "4\n"} {
+		write(t, w, s)
+		if err := w.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files := rotatedFiles(t, w)
+	if len(files) != 2 {
+		t.Fatalf("rotated files = %v, want 2", files)
+	}
+	if got := readFile(t, files[0]) + readFile(t, files[1]); got != "3\n4\n" {
+		t.Errorf("kept rotated files = %q, want %q", got, "3\n4\n")
+	}
+	if got := readFile(t, path); got != "" {
+		t.Errorf("current file = %q, want it empty", got)
+	}
+}
+
+func TestWriterCompress(t *testing.T) {
+	fakeClock(t, time.Millisecond)
+	w, _ := newWriter(t, Options{Compress: true})
+
+	write(t, w, "compressed\n")
+	if err := w.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files := rotatedFiles(t, w)
+	if len(files) != 1 || !strings.HasSuffix(files[0], ".log.gz") {
+		t.Fatalf("rotated files = %v, want one .log.gz", files)
+	}
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "compressed\n" {
+		t.Errorf("decompressed = %q, want %q", b, "compressed\n")
+	}
+}
+
+func TestWriterRotateFailure(t *testing.T) {
+	fakeClock(t, 0)
+	w, path := newWriter(t, Options{MaxSize: 10})
+
+	// a directory in place of the rotated file makes the rename fail
+	rotated := strings.TrimSuffix(path, ".log") + "-" + now().Format(timeFormat) + ".log"
+	if err := os.MkdirAll(filepath.Join(rotated, "busy"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	write(t, w, "first\n")
+	for _, s := range []string{"second\n", "third\n"} {
+		n, err := io.WriteString(w, s)
+		if err == nil {
+			t.Error("write rotating into a directory succeeded")
+		}
+		if n != len(s) {
+			t.Errorf("write failing to rotate wrote %d bytes, want %d", n, len(s))
+		}
+	}
+
+	if err := os.RemoveAll(rotated); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, rotated); got != "first\nsecond\nthird\n" {
+		t.Errorf("rotated file = %q, want all the writes", got)
+	}
+}
+
+func TestWriterClosed(t *testing.T) {
+	w, _ := newWriter(t, Options{})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, "closed\n"); err != os.ErrClosed {
+		t.Errorf("write after close = %v, want %v", err, os.ErrClosed)
+	}
+	if err := w.Rotate(); err != os.ErrClosed {
+		t.Errorf("rotate after close = %v, want %v", err, os.ErrClosed)
+	}
+}
@@ -0,0 +1,255 @@
+package rueidisx
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/rueidis"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/tlipoca9/asta/pkg/rueidisx"
+
+// Metrics are the prometheus metrics of the commands run by the clients
+// created with WithMetrics. Their names are prefixed by namespace, e.g.
+// "asta_redis_pipeline_size".
+type Metrics struct {
+	duration     *prometheus.HistogramVec
+	pipelineSize prometheus.Histogram
+}
+
+func NewMetrics(namespace string) *Metrics {
+	return &Metrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "redis_command_duration_seconds",
+			Help:      "Duration of the redis commands, pipelines are labeled with the command \"pipeline\".",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"command", "status"}),
+		pipelineSize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "redis_pipeline_size",
+			Help:      "Number of commands sent by each redis pipeline.",
+			Buckets:   prometheus.ExponentialBuckets(2, 2, 8),
+		}),
+	}
+}
+
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.duration.Describe(ch)
+	m.pipelineSize.Describe(ch)
+}
+
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.duration.Collect(ch)
+	m.pipelineSize.Collect(ch)
+}
+
+type Option func(*core)
+
+// WithTracerProvider uses tp instead of the global tracer provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *core) { c.tracer = tp.Tracer(tracerName) }
+}
+
+// WithMetrics observes every command with m.
+func WithMetrics(m *Metrics) Option {
+	return func(c *core) { c.metrics = m }
+}
+
+// WithAttributes adds attrs to every span, e.g. semconv.ServerAddress.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *core) { c.attrs = append(c.attrs, attrs...) }
+}
+
+// NewClient wraps c so that every command starts a client span, as a child of
+// the span in its context, and is observed by the metrics if any. Streams and
+// subscriptions are passed through untraced.
+func NewClient(c rueidis.Client, opts ...Option) rueidis.Client {
+	cc := core{
+		tracer: otel.GetTracerProvider().Tracer(tracerName),
+		attrs:  []attribute.KeyValue{semconv.DBSystemRedis},
+	}
+	for _, opt := range opts {
+		opt(&cc)
+	}
+	return &client{Client: c, core: cc.with(c)}
+}
+
+type client struct {
+	rueidis.Client
+	core
+}
+
+func (c *client) B() rueidis.Builder {
+	return c.Client.B()
+}
+
+func (c *client) Do(ctx context.Context, cmd rueidis.Completed) rueidis.RedisResult {
+	return c.core.Do(ctx, cmd)
+}
+
+func (c *client) DoMulti(ctx context.Context, multi ...rueidis.Completed) []rueidis.RedisResult {
+	return c.core.DoMulti(ctx, multi...)
+}
+
+func (c *client) Receive(ctx context.Context, subscribe rueidis.Completed, fn func(msg rueidis.PubSubMessage)) error {
+	return c.Client.Receive(ctx, subscribe, fn)
+}
+
+func (c *client) Close() {
+	c.Client.Close()
+}
+
+func (c *client) DoCache(ctx context.Context, cmd rueidis.Cacheable, ttl time.Duration) rueidis.RedisResult {
+	ctx, span, done := c.start(ctx, commandName(cmd.Commands()), 1)
+	resp := c.Client.DoCache(ctx, cmd, ttl)
+	span.SetAttributes(attribute.Bool("db.redis.cache_hit", resp.IsCacheHit()))
+	done(resp.Error())
+	return resp
+}
+
+func (c *client) DoMultiCache(ctx context.Context, multi ...rueidis.CacheableTTL) []rueidis.RedisResult {
+	ctx, span, done := c.start(ctx, "pipeline", len(multi))
+	resps := c.Client.DoMultiCache(ctx, multi...)
+	hits := 0
+	for _, resp := range resps {
+		if resp.IsCacheHit() {
+			hits++
+		}
+	}
+	span.SetAttributes(attribute.Int("db.redis.cache_hits", hits))
+	done(firstError(resps))
+	return resps
+}
+
+func (c *client) Dedicated(fn func(rueidis.DedicatedClient) error) error {
+	return c.Client.Dedicated(func(dc rueidis.DedicatedClient) error {
+		return fn(c.dedicated(dc))
+	})
+}
+
+func (c *client) Dedicate() (rueidis.DedicatedClient, func()) {
+	dc, cancel := c.Client.Dedicate()
+	return c.dedicated(dc), cancel
+}
+
+func (c *client) Nodes() map[string]rueidis.Client {
+	nodes := c.Client.Nodes()
+	ret := make(map[string]rueidis.Client, len(nodes))
+	for addr, node := range nodes {
+		ret[addr] = &client{Client: node, core: c.core.with(node)}
+	}
+	return ret
+}
+
+func (c *client) dedicated(dc rueidis.DedicatedClient) rueidis.DedicatedClient {
+	return &dedicatedClient{DedicatedClient: dc, core: c.core.with(dc)}
+}
+
+type dedicatedClient struct {
+	rueidis.DedicatedClient
+	core
+}
+
+func (c *dedicatedClient) B() rueidis.Builder {
+	return c.DedicatedClient.B()
+}
+
+func (c *dedicatedClient) Do(ctx context.Context, cmd rueidis.Completed) rueidis.RedisResult {
+	return c.core.Do(ctx, cmd)
+}
+
+func (c *dedicatedClient) DoMulti(ctx context.Context, multi ...rueidis.Completed) []rueidis.RedisResult {
+	return c.core.DoMulti(ctx, multi...)
+}
+
+func (c *dedicatedClient) Receive(
+	ctx context.Context,
+	subscribe rueidis.Completed,
+	fn func(msg rueidis.PubSubMessage),
+) error {
+	return c.DedicatedClient.Receive(ctx, subscribe, fn)
+}
+
+func (c *dedicatedClient) Close() {
+	c.DedicatedClient.Close()
+}
+
+// core traces the commands shared by clients and dedicated clients.
+type core struct {
+	rueidis.CoreClient
+
+	tracer  trace.Tracer
+	metrics *Metrics
+	attrs   []attribute.KeyValue
+}
+
+func (c core) with(cc rueidis.CoreClient) core {
+	c.CoreClient = cc
+	return c
+}
+
+func (c core) Do(ctx context.Context, cmd rueidis.Completed) rueidis.RedisResult {
+	ctx, _, done := c.start(ctx, commandName(cmd.Commands()), 1)
+	resp := c.CoreClient.Do(ctx, cmd)
+	done(resp.Error())
+	return resp
+}
+
+func (c core) DoMulti(ctx context.Context, multi ...rueidis.Completed) []rueidis.RedisResult {
+	ctx, _, done := c.start(ctx, "pipeline", len(multi))
+	resps := c.CoreClient.DoMulti(ctx, multi...)
+	done(firstError(resps))
+	return resps
+}
+
+// start starts the span of a command, or of a pipeline of size commands.
+// The command name must be read before running the command, as rueidis
+// recycles it afterwards.
+func (c core) start(ctx context.Context, name string, size int) (context.Context, trace.Span, func(err error)) {
+	begin := time.Now()
+	ctx, span := c.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(c.attrs...))
+	if name == "pipeline" {
+		span.SetAttributes(attribute.Int("db.redis.pipeline_size", size))
+	} else {
+		span.SetAttributes(semconv.DBOperationName(name))
+	}
+	return ctx, span, func(err error) {
+		status := "ok"
+		if err != nil && !rueidis.IsRedisNil(err) {
+			status = "error"
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+		if c.metrics != nil {
+			c.metrics.duration.WithLabelValues(name, status).Observe(time.Since(begin).Seconds())
+			if name == "pipeline" {
+				c.metrics.pipelineSize.Observe(float64(size))
+			}
+		}
+	}
+}
+
+func commandName(cmds []string) string {
+	if len(cmds) == 0 {
+		return "unknown"
+	}
+	return strings.ToUpper(cmds[0])
+}
+
+func firstError(resps []rueidis.RedisResult) error {
+	for _, resp := range resps {
+		if err := resp.Error(); err != nil && !rueidis.IsRedisNil(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package rueidisx
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/redis/rueidis"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fakeRedis serves RESP2 on a local address: GET of the key "missing" replies
+// nil, of the key "fail" an error, every other command "OK".
+func fakeRedis(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveRedis(conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func serveRedis(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		reply := "+OK\r\n"
+		if strings.EqualFold(args[0], "GET") && len(args) > 1 {
+			switch args[1] {
+			case "missing":
+				reply = "$-1\r\n"
+			case "fail":
+				reply = "-ERR boom\r\n"
+			}
+		}
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("bad command %q", line)
+	}
+	args := make([]string, n)
+	for i := range args {
+		if _, err := r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
+
+func TestClient(t *testing.T) {
+	addr := fakeRedis(t)
+	cli, err := rueidis.NewClient(rueidis.ClientOption{
+		InitAddress:       []string{addr},
+		AlwaysRESP2:       true,
+		DisableCache:      true,
+		ForceSingleClient: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cli.Close)
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	metrics := NewMetrics("test")
+	cli = NewClient(cli, WithTracerProvider(tp), WithMetrics(metrics), WithAttributes(semconv.ServerAddress("cache")))
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	tests := []struct {
+		key    string
+		status codes.Code
+	}{
+		{"found", codes.Unset},
+		{"missing", codes.Unset},
+		{"fail", codes.Error},
+	}
+	for _, tt := range tests {
+		_ = cli.Do(ctx, cli.B().Get().Key(tt.key).Build())
+	}
+	resps := cli.DoMulti(ctx, cli.B().Set().Key("k").Value("v").Build(), cli.B().Get().Key("fail").Build())
+	if len(resps) != 2 || resps[1].Error() == nil {
+		t.Fatalf("pipeline responses = %v, want the error of the second command", resps)
+	}
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != len(tests)+2 {
+		t.Fatalf("ended spans = %d, want %d", len(spans), len(tests)+2)
+	}
+	for i, span := range spans[:len(tests)+1] {
+		name, status := "GET", codes.Error
+		if i < len(tests) {
+			status = tests[i].status
+		} else {
+			name = "pipeline"
+		}
+		if span.Name() != name {
+			t.Errorf("span %d name = %q, want %q", i, span.Name(), name)
+		}
+		if span.SpanKind() != trace.SpanKindClient {
+			t.Errorf("span %d kind = %v, want client", i, span.SpanKind())
+		}
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("span %d is not a child of the span of the context", i)
+		}
+		if span.Status().Code != status {
+			t.Errorf("span %d status = %v, want %v", i, span.Status().Code, status)
+		}
+		attrs := attribute.NewSet(span.Attributes()...)
+		for _, want := range []attribute.KeyValue{semconv.DBSystemRedis, semconv.ServerAddress("cache")} {
+			if got, ok := attrs.Value(want.Key); !ok || got != want.Value {
+				t.Errorf("span %d attribute %s = %v, want %v", i, want.Key, got.Emit(), want.Value.Emit())
+			}
+		}
+	}
+
+	if got := testutil.CollectAndCount(metrics.duration); got != 3 {
+		t.Errorf("duration series = %d, want 3", got)
+	}
+	for _, tt := range []struct {
+		command, status string
+		want            uint64
+	}{
+		{"GET", "ok", 2},
+		{"GET", "error", 1},
+		{"pipeline", "error", 1},
+	} {
+		var m dto.Metric
+		if err := metrics.duration.WithLabelValues(tt.command, tt.status).(prometheus.Metric).Write(&m); err != nil {
+			t.Fatal(err)
+		}
+		if got := m.GetHistogram().GetSampleCount(); got != tt.want {
+			t.Errorf("%s %s observations = %d, want %d", tt.command, tt.status, got, tt.want)
+		}
+	}
+}